@@ -2,13 +2,76 @@ package main
 
 import (
 	"browser-agent/internal/application"
+	"browser-agent/internal/schema"
 	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 )
 
+// taskList — флаг -task, который можно указать несколько раз
+type taskList []string
+
+func (t *taskList) String() string { return strings.Join(*t, "; ") }
+
+func (t *taskList) Set(v string) error {
+	*t = append(*t, v)
+	return nil
+}
+
 func main() {
+	var tasks taskList
+	flag.Var(&tasks, "task", "задача для batch-режима (можно указать несколько раз)")
+	tasksFile := flag.String("tasks-file", "", "файл с задачами, по одной на строку ('-' = stdin)")
+	headless := flag.Bool("headless", false, "запустить браузер без окна (только batch-режим)")
+	taskTimeout := flag.Duration("task-timeout", 0, "дедлайн на одну задачу, например 5m (0 — без ограничения)")
+	schemaFile := flag.String("schema", "", "JSON Schema результата задач batch-режима (поле output)")
+	flag.Parse()
+
 	ctx := context.Background()
-	err := application.Run(ctx)
+
+	// Без задач во флагах — обычный интерактивный REPL
+	if len(tasks) == 0 && *tasksFile == "" {
+		err := application.Run(ctx)
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	if *tasksFile != "" {
+		fileTasks, err := application.ReadTasks(*tasksFile)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		tasks = append(tasks, fileTasks...)
+	}
+
+	var resultSchema map[string]any
+	if *schemaFile != "" {
+		s, err := schema.Load(*schemaFile)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		resultSchema = s
+	}
+
+	// Ctrl+C / SIGTERM отменяют текущую задачу и весь прогон
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+
+	err := application.RunBatch(ctx, application.BatchOptions{
+		Tasks:       tasks,
+		Headless:    *headless,
+		TaskTimeout: *taskTimeout,
+		Output:      os.Stdout,
+		Schema:      resultSchema,
+	})
+	stop() // os.Exit не выполняет defer
 	if err != nil {
-		panic(err)
+		log.Printf("❌ %v", err)
+		os.Exit(1)
 	}
 }
@@ -27,7 +27,7 @@ func main() {
 
 	// Стартовая страница
 	startURL := "https:/mail.yandex.ru"
-	if err := browserSvc.Navigate(startURL); err != nil {
+	if err := browserSvc.Navigate(ctx, startURL); err != nil {
 		log.Printf("⚠️ Ошибка навигации: %v", err)
 	}
 
@@ -40,7 +40,7 @@ func main() {
 		// 1. СКАНИРОВАНИЕ (Observe)
 		// Делаем это в начале каждого цикла, чтобы видеть актуальное состояние
 		fmt.Println("\n👀 Сканирую страницу...")
-		state, err := browserSvc.Observe()
+		state, err := browserSvc.Observe(ctx)
 		if err != nil {
 			fmt.Printf("⚠️ Ошибка Observe: %v\n", err)
 		} else {
@@ -90,7 +90,7 @@ func main() {
 				url = "https://" + url
 			}
 			fmt.Printf("🌐 Переход на %s...\n", url)
-			actionErr = browserSvc.Navigate(url)
+			actionErr = browserSvc.Navigate(ctx, url)
 
 		case "c", "click":
 			if len(args) == 0 {
@@ -103,7 +103,7 @@ func main() {
 				continue
 			}
 			fmt.Printf("🖱️ Клик по ID [%d]...\n", id)
-			actionErr = browserSvc.Click(id)
+			actionErr = browserSvc.Click(ctx, id)
 
 		case "t", "type":
 			if len(args) < 2 {
@@ -117,7 +117,7 @@ func main() {
 			}
 			text := strings.Join(args[1:], " ") // Собираем остальной текст
 			fmt.Printf("⌨️ Ввод '%s' в ID [%d]...\n", text, id)
-			actionErr = browserSvc.Type(id, text)
+			actionErr = browserSvc.Type(ctx, id, text)
 
 		case "s", "scroll":
 			direction := "down"
@@ -125,11 +125,11 @@ func main() {
 				direction = args[0]
 			}
 			fmt.Printf("📜 Скролл %s...\n", direction)
-			actionErr = browserSvc.Scroll(direction)
+			actionErr = browserSvc.Scroll(ctx, direction)
 
 		case "b", "back":
 			fmt.Println("⬅️ Назад...")
-			actionErr = browserSvc.GoBack()
+			actionErr = browserSvc.GoBack(ctx)
 
 		case "k", "key":
 			if len(args) == 0 {
@@ -138,7 +138,7 @@ func main() {
 			}
 			key := args[0]
 			fmt.Printf("🎹 Нажатие клавиши: %s...\n", key)
-			actionErr = browserSvc.PressKey(key)
+			actionErr = browserSvc.PressKey(ctx, key)
 
 		case "help", "h", "?":
 			printHelp()
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"browser-agent/internal/application"
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "адрес HTTP API")
+	headless := flag.Bool("headless", true, "запустить браузер без окна")
+	flag.Parse()
+
+	// Ctrl+C / SIGTERM: отменяется текущая задача и сервер гасится
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := application.RunServer(ctx, application.ServerOptions{
+		Addr:     *addr,
+		Headless: *headless,
+	})
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+}
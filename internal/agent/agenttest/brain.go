@@ -0,0 +1,169 @@
+// Package agenttest — детерминированные Brain и Browser для офлайн-тестов оркестратора.
+//
+// ScriptedBrain проигрывает заранее записанные ответы модели (из кода или из
+// JSON-фикстуры), Browser имитирует страницы без Chromium.
+package agenttest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"browser-agent/internal/entity"
+)
+
+// ErrScriptExhausted — модель "ответила" всё, что было в сценарии
+var ErrScriptExhausted = errors.New("agenttest: script exhausted")
+
+// Condition — когда шаг сценария можно проиграть. Пустые поля не проверяются.
+type Condition struct {
+	URLContains string `json:"url_contains,omitempty"`
+	DOMContains string `json:"dom_contains,omitempty"`
+}
+
+func (c *Condition) match(state *entity.BrowserState) bool {
+	if c == nil {
+		return true
+	}
+	if c.URLContains != "" && !strings.Contains(state.URL, c.URLContains) {
+		return false
+	}
+	if c.DOMContains != "" && !strings.Contains(state.DOMSummary, c.DOMContains) {
+		return false
+	}
+	return true
+}
+
+// ScriptCall — один вызов инструмента в сценарии
+type ScriptCall struct {
+	Name      string                 `json:"name"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+	Reasoning string                 `json:"reasoning,omitempty"`
+}
+
+// ScriptStep — ответ модели на один Step: пачка вызовов или ошибка LLM.
+// Пока When не выполняется, Brain возвращает пустой ответ и шаг не расходуется
+// (как модель, которая ждет загрузки страницы).
+type ScriptStep struct {
+	When  *Condition   `json:"when,omitempty"`
+	Calls []ScriptCall `json:"calls,omitempty"`
+	Error string       `json:"error,omitempty"`
+}
+
+// Script — формат JSON-фикстуры
+type Script struct {
+	Steps []ScriptStep `json:"steps"`
+}
+
+// ScriptedBrain реализует agent.Brain, проигрывая Script по порядку
+type ScriptedBrain struct {
+	mu    sync.Mutex
+	steps []ScriptStep
+	next  int
+
+	// Наблюдаемое состояние — для проверок в тестах
+	Resets   int
+	Tasks    []string
+	States   []entity.BrowserState
+	Memory   []string
+	Schema   map[string]any
+	Recorded []entity.ActionRecord
+}
+
+func NewScriptedBrain(steps ...ScriptStep) *ScriptedBrain {
+	return &ScriptedBrain{steps: steps}
+}
+
+// LoadScript читает сценарий из JSON-фикстуры
+func LoadScript(path string) (*ScriptedBrain, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var script Script
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("agenttest: parse %s: %w", path, err)
+	}
+	return NewScriptedBrain(script.Steps...), nil
+}
+
+func (b *ScriptedBrain) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.Resets++
+	b.Recorded = nil
+}
+
+func (b *ScriptedBrain) Step(ctx context.Context, state *entity.BrowserState, task string) ([]entity.ToolCall, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.Tasks = append(b.Tasks, task)
+	b.States = append(b.States, *state)
+
+	if b.next >= len(b.steps) {
+		return nil, ErrScriptExhausted
+	}
+
+	step := b.steps[b.next]
+	if !step.When.match(state) {
+		return nil, nil
+	}
+	b.next++
+
+	if step.Error != "" {
+		return nil, errors.New(step.Error)
+	}
+
+	calls := make([]entity.ToolCall, 0, len(step.Calls))
+	for _, c := range step.Calls {
+		// Копируем аргументы: оркестратор не должен портить сценарий
+		args := make(map[string]interface{}, len(c.Args))
+		for k, v := range c.Args {
+			args[k] = v
+		}
+		calls = append(calls, entity.ToolCall{Name: c.Name, Args: args, Reasoning: c.Reasoning})
+	}
+	return calls, nil
+}
+
+func (b *ScriptedBrain) RecordAction(call entity.ToolCall, result string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	argsBytes, _ := json.Marshal(call.Args)
+	b.Recorded = append(b.Recorded, entity.ActionRecord{
+		Reasoning: call.Reasoning,
+		Action:    call.Name,
+		Args:      string(argsBytes),
+		Result:    result,
+	})
+}
+
+func (b *ScriptedBrain) SetMemory(facts []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.Memory = facts
+}
+
+func (b *ScriptedBrain) SetSchema(schema map[string]any) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.Schema = schema
+}
+
+// Remaining — сколько шагов сценария еще не проиграно
+func (b *ScriptedBrain) Remaining() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.steps) - b.next
+}
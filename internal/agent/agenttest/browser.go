@@ -0,0 +1,321 @@
+package agenttest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"browser-agent/internal/entity"
+)
+
+// Page — фейковая страница: готовый DOMSummary и реакция на клики
+type Page struct {
+	Title string
+	DOM   string
+	Links map[int]string                // ID элемента → URL, куда ведет клик
+	Texts map[int]string                // ID элемента → текст для read_text
+	Data  map[int]*entity.ExtractedData // ID элемента → таблица/список для extract_*; 0 — без ID
+
+	// Stubborn — элементы, на которые действует только один способ click/type
+	// (например, "js_click" для кнопки под оверлеем); "never" — ни один.
+	// Probe помечает их Obscured. Остальные клики меняют страницу, ввод попадает в поле.
+	Stubborn map[int]entity.ActionStrategy
+	// Silent — элементы, клик по которым проходит, но ничего видимого не меняет
+	Silent map[int]bool
+	// Offscreen — элементы ниже экрана: до первого клика (он прокручивает к ним)
+	// Probe помечает их Obscured, чтобы проверить, что повтор решается не по пробе до клика
+	Offscreen map[int]bool
+}
+
+// Browser реализует agent.Browser без Chromium. Все действия пишутся в Calls.
+type Browser struct {
+	mu sync.Mutex
+
+	Pages map[string]*Page
+	URL   string
+	Tabs  int
+
+	// Calls — журнал действий в виде "click 3", "type 2 hello", "navigate https://..."
+	Calls []string
+	// Waits — все ожидания по порядку: и после действий, и из wait_for
+	Waits []entity.WaitPolicy
+
+	ObserveErr error            // Ошибка для Observe (проверка observe_failed)
+	Fail       map[string]error // Имя действия → ошибка, которую оно вернет
+
+	back     []string
+	revision int            // Счетчик изменений страницы для Probe
+	values   map[int]string // Что попало в поля через type
+	scrolled map[int]bool   // Элементы, к которым клик уже прокрутил
+}
+
+func NewBrowser(startURL string, pages map[string]*Page) *Browser {
+	return &Browser{
+		Pages: pages,
+		URL:   startURL,
+		Tabs:  1,
+		Fail:  map[string]error{},
+	}
+}
+
+func (b *Browser) Observe(ctx context.Context) (*entity.BrowserState, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.ObserveErr != nil {
+		return nil, b.ObserveErr
+	}
+
+	page, ok := b.Pages[b.URL]
+	if !ok {
+		return &entity.BrowserState{URL: b.URL, DOMSummary: "Page is empty"}, nil
+	}
+	return &entity.BrowserState{URL: b.URL, Title: page.Title, DOMSummary: page.DOM}, nil
+}
+
+func (b *Browser) Click(ctx context.Context, id int) error {
+	return b.ClickWith(ctx, id, entity.StrategyDefault)
+}
+
+// ClickWith пишет в журнал "click 3" для основного способа и "click 3 js_click" для запасных
+func (b *Browser) ClickWith(ctx context.Context, id int, strategy entity.ActionStrategy) error {
+	return b.do(ctx, "click", withStrategy(fmt.Sprintf("click %d", id), strategy), func() error {
+		if b.scrolled == nil {
+			b.scrolled = map[int]bool{}
+		}
+		b.scrolled[id] = true
+		if !b.works(id, strategy) {
+			return nil
+		}
+		if url, ok := b.page().Links[id]; ok {
+			b.goTo(url)
+		} else if !b.page().Silent[id] {
+			b.revision++
+		}
+		return nil
+	})
+}
+
+func (b *Browser) DoubleClick(ctx context.Context, id int) error {
+	return b.do(ctx, "double_click", fmt.Sprintf("double_click %d", id), nil)
+}
+
+func (b *Browser) RightClick(ctx context.Context, id int) error {
+	return b.do(ctx, "right_click", fmt.Sprintf("right_click %d", id), nil)
+}
+
+func (b *Browser) Hover(ctx context.Context, id int) error {
+	return b.do(ctx, "hover", fmt.Sprintf("hover %d", id), nil)
+}
+
+func (b *Browser) Drag(ctx context.Context, fromID, toID int) error {
+	return b.do(ctx, "drag", fmt.Sprintf("drag %d %d", fromID, toID), nil)
+}
+
+func (b *Browser) Type(ctx context.Context, id int, text string) error {
+	return b.TypeWith(ctx, id, text, entity.StrategyDefault)
+}
+
+func (b *Browser) TypeWith(ctx context.Context, id int, text string, strategy entity.ActionStrategy) error {
+	return b.do(ctx, "type", withStrategy(fmt.Sprintf("type %d %s", id, text), strategy), func() error {
+		if b.works(id, strategy) {
+			if b.values == nil {
+				b.values = map[int]string{}
+			}
+			b.values[id] = text
+		}
+		return nil
+	})
+}
+
+// Probe в журнал не пишется: это не действие
+func (b *Browser) Probe(ctx context.Context, id int) (*entity.ActionProbe, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, obscured := b.page().Stubborn[id]
+	obscured = obscured || (b.page().Offscreen[id] && !b.scrolled[id])
+	return &entity.ActionProbe{URL: b.URL, TargetID: "fake-target", Mutations: b.revision, Found: true, Obscured: obscured, Value: b.values[id]}, nil
+}
+
+func (b *Browser) ReadText(ctx context.Context, id int) (string, error) {
+	var text string
+	err := b.do(ctx, "read_text", fmt.Sprintf("read_text %d", id), func() error {
+		t, ok := b.page().Texts[id]
+		if !ok {
+			return fmt.Errorf("element %d not found", id)
+		}
+		text = t
+		return nil
+	})
+	return text, err
+}
+
+func (b *Browser) ExtractTable(ctx context.Context, id, maxRows int) (*entity.ExtractedData, error) {
+	return b.extract(ctx, "extract_table", id, maxRows)
+}
+
+func (b *Browser) ExtractList(ctx context.Context, id, maxRows int) (*entity.ExtractedData, error) {
+	return b.extract(ctx, "extract_list", id, maxRows)
+}
+
+func (b *Browser) extract(ctx context.Context, action string, id, maxRows int) (*entity.ExtractedData, error) {
+	var data *entity.ExtractedData
+	err := b.do(ctx, action, fmt.Sprintf("%s %d %d", action, id, maxRows), func() error {
+		d, ok := b.page().Data[id]
+		if !ok {
+			return fmt.Errorf("no data around element %d", id)
+		}
+		copied := *d
+		data = &copied
+		return nil
+	})
+	return data, err
+}
+
+// SelectOption и SetValue возвращают ровно то, что попросили выбрать или задать
+func (b *Browser) SelectOption(ctx context.Context, id int, label string) (string, error) {
+	err := b.do(ctx, "select_option", fmt.Sprintf("select_option %d %s", id, label), nil)
+	return label, err
+}
+
+func (b *Browser) SetValue(ctx context.Context, id int, value string) (string, error) {
+	err := b.do(ctx, "set_value", fmt.Sprintf("set_value %d %s", id, value), nil)
+	return value, err
+}
+
+func (b *Browser) UploadFile(ctx context.Context, id int, name string) error {
+	return b.do(ctx, "upload_file", fmt.Sprintf("upload_file %d %s", id, name), nil)
+}
+
+func (b *Browser) Scroll(ctx context.Context, direction string) error {
+	return b.do(ctx, "scroll", "scroll "+direction, nil)
+}
+
+func (b *Browser) Navigate(ctx context.Context, url string) error {
+	return b.do(ctx, "navigate", "navigate "+url, func() error {
+		b.goTo(url)
+		return nil
+	})
+}
+
+func (b *Browser) GoBack(ctx context.Context) error {
+	return b.do(ctx, "go_back", "go_back", func() error {
+		if len(b.back) == 0 {
+			return fmt.Errorf("no history")
+		}
+		b.URL = b.back[len(b.back)-1]
+		b.back = b.back[:len(b.back)-1]
+		return nil
+	})
+}
+
+func (b *Browser) CloseTab(ctx context.Context) error {
+	return b.do(ctx, "close_tab", "close_tab", func() error {
+		if b.Tabs <= 1 {
+			return fmt.Errorf("cannot close the only tab")
+		}
+		b.Tabs--
+		return nil
+	})
+}
+
+func (b *Browser) PressKey(ctx context.Context, keyName string) error {
+	return b.do(ctx, "press", "press "+keyName, nil)
+}
+
+// Wait в Calls не пишется: ожидание — не действие. Текст ищется в DOM текущей
+// страницы; остальные условия выполняются сразу. Fail["wait"] — ошибка ожидания.
+func (b *Browser) Wait(ctx context.Context, policy entity.WaitPolicy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.Waits = append(b.Waits, policy)
+	if err := b.Fail["wait"]; err != nil {
+		return err
+	}
+	for _, cond := range policy.Conditions {
+		if cond != entity.WaitText && cond != entity.WaitTextGone {
+			continue
+		}
+		found := strings.Contains(strings.ToLower(b.page().DOM), strings.ToLower(policy.Text))
+		if found != (cond == entity.WaitText) {
+			return fmt.Errorf("%s not reached within %s", cond, policy.Timeout)
+		}
+	}
+	return nil
+}
+
+func (b *Browser) GetCurrentPageInfo() (string, string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.URL, "fake-target"
+}
+
+func (b *Browser) Close() {}
+
+// CallLog возвращает копию журнала действий
+func (b *Browser) CallLog() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.Calls...)
+}
+
+// do — общий путь всех действий: проверка ctx, журнал, подмененная ошибка
+func (b *Browser) do(ctx context.Context, action, entry string, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.Calls = append(b.Calls, entry)
+	if err := b.Fail[action]; err != nil {
+		return err
+	}
+	if fn != nil {
+		return fn()
+	}
+	return nil
+}
+
+// works — сработает ли способ на элементе (вызывать под b.mu)
+func (b *Browser) works(id int, strategy entity.ActionStrategy) bool {
+	only, ok := b.page().Stubborn[id]
+	return !ok || only == strategy
+}
+
+func withStrategy(entry string, strategy entity.ActionStrategy) string {
+	if strategy == entity.StrategyDefault || strategy == "" {
+		return entry
+	}
+	return entry + " " + string(strategy)
+}
+
+// page — текущая страница (вызывать под b.mu)
+func (b *Browser) page() *Page {
+	if p, ok := b.Pages[b.URL]; ok {
+		return p
+	}
+	return &Page{}
+}
+
+// goTo — переход с записью в историю (вызывать под b.mu)
+func (b *Browser) goTo(url string) {
+	b.back = append(b.back, b.URL)
+	b.URL = url
+}
@@ -3,7 +3,9 @@ package agent
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strconv"
@@ -11,19 +13,36 @@ import (
 	"time"
 
 	"browser-agent/internal/entity"
+	"browser-agent/internal/schema"
 )
 
 // Interfaces (дублируем для наглядности, в реальном проекте они в entity или interfaces)
+// Все действия принимают ctx: отмена задачи (Ctrl+C, дедлайн) прерывает и браузерную операцию.
 type Browser interface {
-	Observe() (*entity.BrowserState, error)
-	Click(id int) error
-	Type(id int, text string) error
-	ReadText(id int) (string, error)
-	Scroll(direction string) error
-	Navigate(url string) error
-	GoBack() error
-	CloseTab() error
-	PressKey(keyName string) error
+	Observe(ctx context.Context) (*entity.BrowserState, error)
+	Click(ctx context.Context, id int) error
+	ClickWith(ctx context.Context, id int, strategy entity.ActionStrategy) error
+	DoubleClick(ctx context.Context, id int) error
+	RightClick(ctx context.Context, id int) error
+	Hover(ctx context.Context, id int) error
+	Drag(ctx context.Context, fromID, toID int) error
+	Type(ctx context.Context, id int, text string) error
+	TypeWith(ctx context.Context, id int, text string, strategy entity.ActionStrategy) error
+	// Probe — состояние страницы и элемента (id > 0) для проверки click/type
+	Probe(ctx context.Context, id int) (*entity.ActionProbe, error)
+	ReadText(ctx context.Context, id int) (string, error)
+	ExtractTable(ctx context.Context, id, maxRows int) (*entity.ExtractedData, error)
+	ExtractList(ctx context.Context, id, maxRows int) (*entity.ExtractedData, error)
+	SelectOption(ctx context.Context, id int, label string) (string, error)
+	SetValue(ctx context.Context, id int, value string) (string, error)
+	UploadFile(ctx context.Context, id int, name string) error
+	Scroll(ctx context.Context, direction string) error
+	Navigate(ctx context.Context, url string) error
+	GoBack(ctx context.Context) error
+	CloseTab(ctx context.Context) error
+	PressKey(ctx context.Context, keyName string) error
+	// Wait ждет условий политики (загрузка, тишина сети и DOM, текст на странице)
+	Wait(ctx context.Context, policy entity.WaitPolicy) error
 	GetCurrentPageInfo() (url string, targetID string)
 	Close()
 }
@@ -33,18 +52,55 @@ type Brain interface {
 	Step(ctx context.Context, state *entity.BrowserState, task string) ([]entity.ToolCall, error)
 	// Используем сигнатуру из твоего последнего сообщения
 	RecordAction(call entity.ToolCall, result string)
+	// SetMemory передает актуальные факты из памяти агента перед каждым шагом
+	SetMemory(facts []string)
+	// SetSchema задает JSON Schema результата задачи (nil — без схемы)
+	SetSchema(schema map[string]any)
 }
 
 // Orchestrator связывает Мозг и Браузер
 type Orchestrator struct {
 	Browser Browser
 	Brain   Brain
+
+	// Events — шина событий (шаги, наблюдения, действия) для UI и API-сервера
+	Events *EventBus
+
+	// Memory — факты из инструмента memorize. При PersistMemory они
+	// переживают задачу и доступны следующим задачам сессии.
+	Memory        *Memory
+	PersistMemory bool
+
+	// Out — куда печатается ход выполнения (STEP, Reasoning, Action...).
+	// В batch-режиме сюда подставляется stderr, чтобы stdout остался под JSON.
+	Out io.Writer
+
+	MaxSteps int // Защита от бесконечного цикла
+
+	// Retry — проверка click/type и повторы другими способами; итог проверки
+	// дописывается в ActionRecord.Result ("Success (verified: URL changed)")
+	Retry RetryPolicy
+
+	// Waits — чего ждать после каждого действия (имя инструмента → политика)
+	Waits map[string]entity.WaitPolicy
+
+	extracted []entity.ExtractedData // Данные extract_table / extract_list текущей задачи
+	schema    map[string]any         // JSON Schema результата текущей задачи
+	output    json.RawMessage        // Принятый result из submit_task_result
+	task      string                 // Текущая задача и шаг — для событий изнутри действий
+	step      int
 }
 
 func New(b Browser, llm Brain) *Orchestrator {
 	return &Orchestrator{
-		Browser: b,
-		Brain:   llm,
+		Browser:  b,
+		Brain:    llm,
+		Events:   NewEventBus(),
+		Memory:   NewMemory(),
+		Out:      os.Stdout,
+		MaxSteps: 30,
+		Retry:    DefaultRetryPolicy(),
+		Waits:    DefaultWaitPolicies(),
 	}
 }
 
@@ -69,44 +125,88 @@ func (o *Orchestrator) Start() {
 		}
 
 		// Запуск выполнения задачи
-		o.RunTask(userInput)
+		o.RunTask(context.Background(), userInput)
 	}
 }
 
-// RunTask выполняет одну конкретную задачу до победного
-func (o *Orchestrator) RunTask(task string) {
-	ctx := context.Background()
+// RunTask выполняет одну конкретную задачу до победного и возвращает её итог.
+// Отмена ctx прерывает текущий запрос к LLM и действие браузера, задача
+// завершается со статусом cancelled.
+func (o *Orchestrator) RunTask(ctx context.Context, task string) *entity.TaskResult {
+	return o.RunTaskWithSchema(ctx, task, nil)
+}
 
+// RunTaskWithSchema — то же, но результат задачи должен соответствовать JSON Schema:
+// модель сдает его аргументом result, несоответствие возвращается ей ошибкой,
+// а принятый JSON попадает в TaskResult.Output.
+func (o *Orchestrator) RunTaskWithSchema(ctx context.Context, task string, resultSchema map[string]any) *entity.TaskResult {
 	// 1. Сбрасываем память мозга для новой задачи
 	o.Brain.Reset()
-	fmt.Printf("🎯 Принята задача: %s\n", task)
+	o.Brain.SetSchema(resultSchema)
+	if !o.PersistMemory {
+		o.Memory.Clear()
+	}
+	o.extracted = nil
+	o.schema = resultSchema
+	o.output = nil
+	o.task, o.step = task, 0
+	o.emit(Event{Type: EventTaskStarted, Task: task})
+
+	result := o.run(ctx, task)
+	result.FinalURL, _ = o.Browser.GetCurrentPageInfo()
+	result.Memory = o.Memory.Facts()
+	result.Extracted = o.extracted
+	result.Output = o.output
+
+	o.emit(Event{Type: EventTaskFinished, Task: task, Step: result.Steps, Result: result})
+	return result
+}
 
-	step := 0
-	maxSteps := 30 // Защита от бесконечного цикла
+// run — основной цикл OBSERVE → THINK → ACT
+func (o *Orchestrator) run(ctx context.Context, task string) *entity.TaskResult {
+	result := &entity.TaskResult{Task: task, History: []entity.ActionRecord{}}
 
-	for step < maxSteps {
-		step++
-		fmt.Printf("\n--- STEP %d ---\n", step)
+	for step := 1; step <= o.MaxSteps; step++ {
+		if ctx.Err() != nil {
+			return cancelled(result, ctx.Err())
+		}
+		result.Steps = step
+		o.step = step
+		o.emit(Event{Type: EventStepStarted, Task: task, Step: step})
 
 		// A. OBSERVE (Глаза)
-		state, err := o.Browser.Observe()
+		state, err := o.Browser.Observe(ctx)
 		if err != nil {
+			if ctx.Err() != nil {
+				return cancelled(result, ctx.Err())
+			}
 			log.Printf("❌ Ошибка наблюдения браузера: %v", err)
-			return
+			result.Status = entity.TaskObserveFailed
+			result.Error = fmt.Sprintf("observe failed: %v", err)
+			return result
 		}
-		fmt.Printf("🌍 URL: %s | Title: %s\n", state.URL, state.Title)
+		o.emit(Event{Type: EventObservation, Task: task, Step: step, URL: state.URL, Title: state.Title})
 
 		// B. THINK (Мозг)
+		o.Brain.SetMemory(o.Memory.Facts())
 		toolCalls, err := o.Brain.Step(ctx, state, task)
 		if err != nil {
+			if ctx.Err() != nil {
+				return cancelled(result, ctx.Err())
+			}
 			log.Printf("🧠 Ошибка LLM: %v", err)
-			time.Sleep(2 * time.Second)
+			o.emit(Event{Type: EventToolCalls, Task: task, Step: step, Error: err.Error()})
+			if err := sleep(ctx, 2*time.Second); err != nil {
+				return cancelled(result, err)
+			}
 			continue // Пробуем еще раз
 		}
+		o.emit(Event{Type: EventToolCalls, Task: task, Step: step, Calls: toolCalls})
 
 		if len(toolCalls) == 0 {
-			fmt.Println("🤔 Агент задумался (нет действий)...")
-			time.Sleep(2 * time.Second)
+			if err := sleep(ctx, 2*time.Second); err != nil {
+				return cancelled(result, err)
+			}
 			continue
 		}
 
@@ -114,115 +214,205 @@ func (o *Orchestrator) RunTask(task string) {
 		missionComplete := false
 
 		for _, call := range toolCalls {
-			fmt.Printf("💭 Reasoning: %s\n", call.Reasoning)
-			fmt.Printf("⚡ Action: %s %+v\n", call.Name, call.Args)
-
 			// Выполняем действие и получаем результат строкой
-			resultStr := o.executeTool(call)
-
-			fmt.Printf("✅ Result: %s\n", resultStr)
+			resultStr := o.executeTool(ctx, call)
 
 			// D. RECORD (Память)
 			o.Brain.RecordAction(call, resultStr)
+			record := newActionRecord(call, resultStr)
+			result.History = append(result.History, record)
+			o.emit(Event{Type: EventToolExecuted, Task: task, Step: step, Action: &record})
 
-			// Если задача выполнена - прерываем цикл
-			if call.Name == "submit_task_result" {
+			if ctx.Err() != nil {
+				return cancelled(result, ctx.Err())
+			}
+
+			// Если задача выполнена - прерываем цикл (отклоненный по схеме
+			// результат — это ошибка в истории, модель исправит его на следующем шаге)
+			if call.Name == "submit_task_result" && !isError(resultStr) {
 				missionComplete = true
+				result.FinalReport = finalReport(call.Args)
 			}
 
-			switch call.Name {
-			case "click", "press":
-				// Если это массив действий, делаем паузу маленькой
-				if len(toolCalls) > 1 {
-					time.Sleep(100 * time.Millisecond) // 0.1 сек (быстро прокликиваем)
-				} else {
-					time.Sleep(2 * time.Second) // Одиночный клик может быть навигацией
+			// Ждем реакции страницы: на быстрой — миллисекунды, на медленной — до таймаута политики
+			if !isError(resultStr) && !o.settlesItself(call.Name) {
+				if err := o.settle(ctx, call.Name); err != nil {
+					return cancelled(result, err)
 				}
-
-			case "type":
-				time.Sleep(50 * time.Millisecond)
-
-			case "navigate":
-				time.Sleep(3 * time.Second) // Тут точно ждем
 			}
 		}
 
 		if missionComplete {
-			fmt.Println("\n🎉 ЗАДАЧА ВЫПОЛНЕНА! Готов к следующей.")
-			break
+			result.Status = entity.TaskCompleted
+			return result
 		}
 	}
 
-	if step >= maxSteps {
-		fmt.Println("⚠️ Превышен лимит шагов. Остановка.")
-	}
+	result.Status = entity.TaskStepLimit
+	result.Error = fmt.Sprintf("step limit (%d) exceeded", o.MaxSteps)
+	return result
+}
+
+// cancelled помечает задачу как отмененную (Ctrl+C, дедлайн или отмена через API)
+func cancelled(result *entity.TaskResult, err error) *entity.TaskResult {
+	result.Status = entity.TaskCancelled
+	result.Error = err.Error()
+	return result
 }
 
 // executeTool маршрутизирует вызов к методам браузера
-func (o *Orchestrator) executeTool(call entity.ToolCall) string {
+func (o *Orchestrator) executeTool(ctx context.Context, call entity.ToolCall) string {
 	var err error
 	var output string = "Success"
 
 	switch call.Name {
-	case "click":
-		if id, ok := getInt(call.Args, "id"); ok {
-			err = o.Browser.Click(id)
-		} else {
+	case "click", "double_click", "right_click", "hover":
+		id, ok := getInt(call.Args, "id")
+		if !ok {
 			err = fmt.Errorf("missing or invalid 'id'")
+			break
+		}
+		switch call.Name {
+		case "click":
+			output, err = o.verifiedClick(ctx, id)
+		case "double_click":
+			err = o.Browser.DoubleClick(ctx, id)
+		case "right_click":
+			err = o.Browser.RightClick(ctx, id)
+		case "hover":
+			err = o.Browser.Hover(ctx, id)
+		}
+
+	case "drag":
+		fromID, okFrom := getInt(call.Args, "from_id")
+		toID, okTo := getInt(call.Args, "to_id")
+		if okFrom && okTo {
+			err = o.Browser.Drag(ctx, fromID, toID)
+		} else {
+			err = fmt.Errorf("missing 'from_id' or 'to_id'")
 		}
 
 	case "type":
 		id, okId := getInt(call.Args, "id")
 		text, okText := getString(call.Args, "text")
 		if okId && okText {
-			err = o.Browser.Type(id, text)
+			output, err = o.verifiedType(ctx, id, text)
 		} else {
 			err = fmt.Errorf("missing 'id' or 'text'")
 		}
 
+	case "select_option":
+		id, okId := getInt(call.Args, "id")
+		option, okOption := getString(call.Args, "option")
+		if !okId || !okOption {
+			err = fmt.Errorf("missing 'id' or 'option'")
+			break
+		}
+		var chosen string
+		if chosen, err = o.Browser.SelectOption(ctx, id, option); err == nil {
+			output = fmt.Sprintf("Selected %q in element %d", chosen, id)
+		}
+
+	case "set_value":
+		id, okId := getInt(call.Args, "id")
+		value, okValue := getString(call.Args, "value")
+		if !okId || !okValue {
+			err = fmt.Errorf("missing 'id' or 'value'")
+			break
+		}
+		var actual string
+		if actual, err = o.Browser.SetValue(ctx, id, value); err == nil {
+			// Ползунок округляет значение до шага — модель должна видеть итог
+			output = fmt.Sprintf("Element %d value is now %q", id, actual)
+		}
+
+	case "upload_file":
+		id, okId := getInt(call.Args, "id")
+		file, okFile := getString(call.Args, "file")
+		if okId && okFile {
+			err = o.Browser.UploadFile(ctx, id, file)
+		} else {
+			err = fmt.Errorf("missing 'id' or 'file'")
+		}
+
 	case "scroll":
 		if dir, ok := getString(call.Args, "direction"); ok {
-			err = o.Browser.Scroll(dir)
+			err = o.Browser.Scroll(ctx, dir)
 		} else {
 			// Дефолт
-			err = o.Browser.Scroll("down")
+			err = o.Browser.Scroll(ctx, "down")
 		}
 
 	case "navigate":
 		if url, ok := getString(call.Args, "url"); ok {
-			err = o.Browser.Navigate(url)
+			err = o.Browser.Navigate(ctx, url)
 		} else {
 			err = fmt.Errorf("missing 'url'")
 		}
 
 	case "press":
 		if key, ok := getString(call.Args, "key"); ok {
-			err = o.Browser.PressKey(key)
+			err = o.Browser.PressKey(ctx, key)
 		} else {
 			err = fmt.Errorf("missing 'key'")
 		}
 
+	case "wait_for":
+		output, err = o.waitFor(ctx, call.Args)
+
 	case "go_back":
-		err = o.Browser.GoBack()
+		err = o.Browser.GoBack(ctx)
+
+	case "close_tab":
+		err = o.Browser.CloseTab(ctx)
+
+	case "read_text":
+		id, ok := getInt(call.Args, "id")
+		if !ok {
+			err = fmt.Errorf("missing or invalid 'id'")
+			break
+		}
+		var text string
+		if text, err = o.Browser.ReadText(ctx, id); err == nil {
+			// Текст уходит в историю — так модель видит его на следующем шаге
+			output = fmt.Sprintf("Text of element %d: %s", id, text)
+		}
+
+	case "extract_table", "extract_list":
+		// ID необязателен: без него берется самая крупная таблица/список страницы
+		id, _ := getInt(call.Args, "id")
+		maxRows, _ := getInt(call.Args, "max_rows")
+		extract := o.Browser.ExtractTable
+		if call.Name == "extract_list" {
+			extract = o.Browser.ExtractList
+		}
+		var data *entity.ExtractedData
+		if data, err = extract(ctx, id, maxRows); err == nil {
+			data.URL, _ = o.Browser.GetCurrentPageInfo()
+			o.extracted = append(o.extracted, *data)
+			// Строки уходят в историю целиком — модель видит их на следующем шаге
+			rows, _ := json.Marshal(map[string]interface{}{"columns": data.Columns, "rows": data.Rows})
+			output = fmt.Sprintf("Extracted %d of %d rows (%s): %s", len(data.Rows), data.Total, data.Kind, rows)
+		}
 
 	case "memorize":
-		if info, ok := getString(call.Args, "info"); ok {
-			return fmt.Sprintf("Saved to memory: %s", info)
+		info, ok := getString(call.Args, "info")
+		if !ok || strings.TrimSpace(info) == "" {
+			err = fmt.Errorf("missing 'info'")
+			break
+		}
+		if !o.Memory.Add(info) {
+			return "Already in memory."
 		}
-		return "Saved info."
+		return fmt.Sprintf("Saved to memory (%d facts total).", o.Memory.Len())
 
 	case "done", "submit_task_result": // Ловим оба имени
-		// Перебираем варианты ключей (приоритет final_report)
-		answer := ""
-		if v, ok := getString(call.Args, "final_report"); ok {
-			answer = v
-		} else if v, ok := getString(call.Args, "answer"); ok {
-			answer = v
-		} else if v, ok := getString(call.Args, "result"); ok {
-			answer = v
+		if call.Name == "submit_task_result" {
+			if err := o.acceptOutput(call.Args); err != nil {
+				return fmt.Sprintf("Error: result rejected, fix it and call submit_task_result again: %v", err)
+			}
 		}
-
-		if answer != "" {
+		if answer := finalReport(call.Args); answer != "" {
 			return fmt.Sprintf("DONE: %s", answer)
 		}
 		return "Task completed."
@@ -237,6 +427,86 @@ func (o *Orchestrator) executeTool(call entity.ToolCall) string {
 	return output
 }
 
+// acceptOutput проверяет аргумент result по схеме задачи и запоминает его.
+// Без схемы принимается любая сдача.
+func (o *Orchestrator) acceptOutput(args map[string]interface{}) error {
+	if o.schema == nil {
+		return nil
+	}
+	value, ok := args["result"]
+	if !ok {
+		return fmt.Errorf("missing 'result'")
+	}
+	// Некоторые модели присылают вложенный объект строкой с JSON. Разбираем только
+	// объект или массив: строка "12345" по строковой схеме — это номер заказа, а не число
+	if s, isString := value.(string); isString && o.schema["type"] != "string" {
+		if trimmed := strings.TrimSpace(s); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			var decoded interface{}
+			if json.Unmarshal([]byte(trimmed), &decoded) == nil {
+				value = decoded
+			}
+		}
+	}
+
+	// Через JSON: числа становятся float64, как их ждет валидатор
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("encode result: %w", err)
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		return fmt.Errorf("decode result: %w", err)
+	}
+	if err := schema.Validate(o.schema, normalized); err != nil {
+		return err
+	}
+	o.output = raw
+	return nil
+}
+
+func isError(result string) bool {
+	return strings.HasPrefix(result, "Error")
+}
+
+// newActionRecord — запись истории для TaskResult (в том же виде, что хранит Мозг)
+func newActionRecord(call entity.ToolCall, result string) entity.ActionRecord {
+	argsBytes, _ := json.Marshal(call.Args)
+	return entity.ActionRecord{
+		Reasoning: call.Reasoning,
+		Action:    call.Name,
+		Args:      string(argsBytes),
+		Result:    result,
+	}
+}
+
+// sleep — пауза, которую можно прервать отменой ctx.
+// Переменная, чтобы тесты могли убрать реальные паузы.
+var sleep = func(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// finalReport достает итоговый отчет из аргументов submit_task_result
+func finalReport(args map[string]interface{}) string {
+	// Перебираем варианты ключей (приоритет final_report)
+	for _, key := range []string{"final_report", "answer", "result"} {
+		if v, ok := getString(args, key); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
 // --- Хелперы для безопасного извлечения типов из map[string]interface{} ---
 
 func getInt(args map[string]interface{}, key string) (int, bool) {
@@ -0,0 +1,688 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"browser-agent/internal/agent/agenttest"
+	"browser-agent/internal/entity"
+)
+
+// Реальные паузы оркестратора (после кликов, навигации, ошибок LLM) в тестах не нужны
+func init() {
+	sleep = func(ctx context.Context, d time.Duration) error { return ctx.Err() }
+}
+
+func shopPages() map[string]*agenttest.Page {
+	return map[string]*agenttest.Page{
+		"about:blank": {Title: "New Tab", DOM: "Page is empty"},
+		"https://shop.test": {
+			Title: "Магазин",
+			DOM:   "[1] <input> [INPUT] Поиск\n[2] <button> [ACTION] Найти\n",
+			Links: map[int]string{2: "https://shop.test/search?q=слон"},
+		},
+		"https://shop.test/search?q=слон": {
+			Title: "Результаты",
+			DOM:   "[5] <link> [NAVIGATE] Слон плюшевый\n",
+			Texts: map[int]string{5: "Слон плюшевый, 100 монет"},
+		},
+	}
+}
+
+func newTestOrchestrator(b Browser, brain Brain) *Orchestrator {
+	o := New(b, brain)
+	o.Out = io.Discard
+	return o
+}
+
+func call(name string, args map[string]interface{}) agenttest.ScriptCall {
+	return agenttest.ScriptCall{Name: name, Args: args}
+}
+
+func TestRunTask_ScriptFixture(t *testing.T) {
+	brain, err := agenttest.LoadScript("testdata/search.json")
+	if err != nil {
+		t.Fatalf("load script: %v", err)
+	}
+	browser := agenttest.NewBrowser("about:blank", shopPages())
+	o := newTestOrchestrator(browser, brain)
+
+	result := o.RunTask(context.Background(), "Узнай цену слона")
+
+	if result.Status != entity.TaskCompleted {
+		t.Fatalf("Expected completed, got %s (%s)", result.Status, result.Error)
+	}
+	if result.FinalReport != "Слон стоит 100 монет" {
+		t.Errorf("Unexpected final report: %q", result.FinalReport)
+	}
+	if result.Steps != 4 {
+		t.Errorf("Expected 4 steps, got %d", result.Steps)
+	}
+	if result.FinalURL != "https://shop.test/search?q=слон" {
+		t.Errorf("Unexpected final URL: %s", result.FinalURL)
+	}
+
+	// Пачка type+click выполнена за один шаг и в исходном порядке
+	wantCalls := []string{
+		"navigate https://shop.test",
+		"type 1 слон",
+		"click 2",
+		"read_text 5",
+	}
+	if got := browser.CallLog(); !reflect.DeepEqual(got, wantCalls) {
+		t.Errorf("Browser calls:\n got %v\nwant %v", got, wantCalls)
+	}
+
+	// Текст из read_text вернулся в историю, факт — в память
+	if len(result.History) != 6 {
+		t.Fatalf("Expected 6 history records, got %d", len(result.History))
+	}
+	if !strings.Contains(result.History[3].Result, "Слон плюшевый, 100 монет") {
+		t.Errorf("read_text result missing text: %q", result.History[3].Result)
+	}
+	if !reflect.DeepEqual(result.Memory, []string{"Слон стоит 100 монет"}) {
+		t.Errorf("Unexpected memory: %v", result.Memory)
+	}
+	if len(brain.Recorded) != len(result.History) {
+		t.Errorf("Brain recorded %d actions, result has %d", len(brain.Recorded), len(result.History))
+	}
+}
+
+func TestRunTask_ConditionWaitsForPage(t *testing.T) {
+	// Шаг с условием не проигрывается, пока в DOM нет нужного текста
+	brain := agenttest.NewScriptedBrain(
+		agenttest.ScriptStep{
+			When:  &agenttest.Condition{DOMContains: "Найти"},
+			Calls: []agenttest.ScriptCall{call("submit_task_result", map[string]interface{}{"final_report": "ok"})},
+		},
+	)
+	browser := agenttest.NewBrowser("about:blank", shopPages())
+	o := newTestOrchestrator(browser, brain)
+	o.MaxSteps = 3
+
+	result := o.RunTask(context.Background(), "Дождись кнопки")
+
+	if result.Status != entity.TaskStepLimit {
+		t.Fatalf("Expected step_limit, got %s", result.Status)
+	}
+	if brain.Remaining() != 1 {
+		t.Errorf("Conditional step must not be consumed, remaining %d", brain.Remaining())
+	}
+	if len(result.History) != 0 {
+		t.Errorf("Expected no actions, got %v", result.History)
+	}
+}
+
+func TestRunTask_StepLimit(t *testing.T) {
+	steps := make([]agenttest.ScriptStep, 10)
+	for i := range steps {
+		steps[i] = agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("scroll", map[string]interface{}{"direction": "down"})}}
+	}
+	brain := agenttest.NewScriptedBrain(steps...)
+	browser := agenttest.NewBrowser("about:blank", shopPages())
+	o := newTestOrchestrator(browser, brain)
+	o.MaxSteps = 4
+
+	result := o.RunTask(context.Background(), "Листай бесконечно")
+
+	if result.Status != entity.TaskStepLimit {
+		t.Fatalf("Expected step_limit, got %s", result.Status)
+	}
+	if result.Steps != 4 || len(browser.CallLog()) != 4 {
+		t.Errorf("Expected 4 steps and 4 scrolls, got %d steps, calls %v", result.Steps, browser.CallLog())
+	}
+	if result.Error == "" {
+		t.Error("Step limit must be reported in Error")
+	}
+}
+
+func TestRunTask_SubmitStopsBatch(t *testing.T) {
+	// Всё, что идет после submit_task_result в той же пачке, выполняется,
+	// но следующего шага уже нет
+	brain := agenttest.NewScriptedBrain(
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{
+			call("submit_task_result", map[string]interface{}{"final_report": "Готово"}),
+			call("scroll", map[string]interface{}{"direction": "down"}),
+		}},
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("click", map[string]interface{}{"id": 1})}},
+	)
+	browser := agenttest.NewBrowser("about:blank", shopPages())
+	o := newTestOrchestrator(browser, brain)
+
+	result := o.RunTask(context.Background(), "Сразу сдай")
+
+	if result.Status != entity.TaskCompleted || result.Steps != 1 {
+		t.Fatalf("Expected completed in 1 step, got %s in %d", result.Status, result.Steps)
+	}
+	if brain.Remaining() != 1 {
+		t.Errorf("Second step must not be requested, remaining %d", brain.Remaining())
+	}
+}
+
+func TestRunTask_ObserveFailure(t *testing.T) {
+	brain := agenttest.NewScriptedBrain()
+	browser := agenttest.NewBrowser("about:blank", shopPages())
+	browser.ObserveErr = errors.New("tab crashed")
+	o := newTestOrchestrator(browser, brain)
+
+	result := o.RunTask(context.Background(), "Что угодно")
+
+	if result.Status != entity.TaskObserveFailed {
+		t.Fatalf("Expected observe_failed, got %s", result.Status)
+	}
+	if !strings.Contains(result.Error, "tab crashed") {
+		t.Errorf("Error must contain cause, got %q", result.Error)
+	}
+}
+
+func TestRunTask_LLMErrorIsRetried(t *testing.T) {
+	brain := agenttest.NewScriptedBrain(
+		agenttest.ScriptStep{Error: "rate limited"},
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("submit_task_result", map[string]interface{}{"final_report": "ok"})}},
+	)
+	browser := agenttest.NewBrowser("about:blank", shopPages())
+	o := newTestOrchestrator(browser, brain)
+
+	result := o.RunTask(context.Background(), "Переживи ошибку")
+
+	if result.Status != entity.TaskCompleted || result.Steps != 2 {
+		t.Fatalf("Expected completed in 2 steps, got %s in %d", result.Status, result.Steps)
+	}
+}
+
+func TestRunTask_ToolErrorsGoToHistory(t *testing.T) {
+	brain := agenttest.NewScriptedBrain(
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{
+			call("click", map[string]interface{}{"id": 99}),
+			call("fly", nil),
+			call("type", map[string]interface{}{"id": 1}),
+		}},
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("submit_task_result", map[string]interface{}{"final_report": "ok"})}},
+	)
+	browser := agenttest.NewBrowser("about:blank", shopPages())
+	browser.Fail["click"] = errors.New("element 99 not found")
+	o := newTestOrchestrator(browser, brain)
+
+	result := o.RunTask(context.Background(), "Ошибайся")
+
+	want := []string{
+		"Error: element 99 not found",
+		"Error: Unknown tool 'fly'",
+		"Error: missing 'id' or 'text'",
+	}
+	for i, w := range want {
+		if result.History[i].Result != w {
+			t.Errorf("History[%d]: got %q, want %q", i, result.History[i].Result, w)
+		}
+	}
+}
+
+func TestRunTask_VerifiesAndRetriesActions(t *testing.T) {
+	pages := shopPages()
+	pages["https://shop.test"].Stubborn = map[int]entity.ActionStrategy{
+		1: entity.StrategyFocusType, // Поле под оверлеем: клавиатурный ввод мимо
+		2: entity.StrategyJSClick,   // Кнопка под оверлеем: мышь не достает
+		3: "never",
+		4: "never",
+	}
+	pages["https://shop.test"].Silent = map[int]bool{6: true, 7: true} // "В корзину": ответ сервера не виден сразу
+	pages["https://shop.test"].Offscreen = map[int]bool{7: true}       // "Оформить заказ" ниже экрана
+	brain := agenttest.NewScriptedBrain(
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{
+			call("navigate", map[string]interface{}{"url": "https://shop.test"}),
+		}},
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{
+			call("type", map[string]interface{}{"id": 1, "text": "слон"}),
+			call("click", map[string]interface{}{"id": 3}),
+			call("type", map[string]interface{}{"id": 4, "text": "x"}),
+			call("click", map[string]interface{}{"id": 6}),
+			call("click", map[string]interface{}{"id": 7}),
+			call("click", map[string]interface{}{"id": 2}),
+		}},
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("submit_task_result", map[string]interface{}{"final_report": "ok"})}},
+	)
+	browser := agenttest.NewBrowser("about:blank", pages)
+	o := newTestOrchestrator(browser, brain)
+	o.Out = nil // Повторы идут событиями, без Out они не печатаются
+
+	events, unsubscribe := o.Events.Subscribe(64)
+	result := o.RunTask(context.Background(), "Найди слона")
+	unsubscribe()
+
+	want := []string{
+		"Success",
+		"Success (verified after focus_type: field value matches)",
+		"Success (unverified: no visible effect — URL, page and element state unchanged; tried default, scroll_into_view, js_click)",
+		`Error: text did not stick: field value is "" (tried default, focus_type, js_value)`,
+		"Success (unverified: no visible effect — URL, page and element state unchanged; tried default)",
+		"Success (unverified: no visible effect — URL, page and element state unchanged; tried default)",
+		"Success (verified after js_click: URL changed)",
+	}
+	for i, w := range want {
+		if result.History[i].Result != w {
+			t.Errorf("History[%d]: got %q, want %q", i, result.History[i].Result, w)
+		}
+	}
+	if result.FinalURL != "https://shop.test/search?q=слон" {
+		t.Errorf("Unexpected final URL: %s", result.FinalURL)
+	}
+
+	// Каждый повтор — событие шага, в котором выполнялось действие
+	var retries []string
+	for e := range events {
+		if e.Type == EventActionRetry {
+			if e.Task != "Найди слона" || e.Step != 2 {
+				t.Errorf("Retry event without task or step: %+v", e)
+			}
+			retries = append(retries, e.Message)
+		}
+	}
+	// type 1: 1 повтор, click 3: 2, type 4: 2, click 2: 2 (сработал js_click)
+	if len(retries) != 7 || !strings.HasPrefix(retries[0], "Ввод в 1 (default)") {
+		t.Errorf("Unexpected retry events: %q", retries)
+	}
+
+	// Клик, дошедший до элемента, не повторяется, даже если эффекта не видно,
+	// в том числе по кнопке, которая до клика была за экраном
+	for _, id := range []string{"click 6", "click 7"} {
+		clicks := 0
+		for _, c := range browser.CallLog() {
+			if c == id || strings.HasPrefix(c, id+" ") {
+				clicks++
+			}
+		}
+		if clicks != 1 {
+			t.Errorf("Silent button %q clicked %d times: %v", id, clicks, browser.CallLog())
+		}
+	}
+
+	// MaxRetries ограничивает число запасных способов, без проверки — один вызов
+	o.Retry.MaxRetries = 1
+	brain2 := agenttest.NewScriptedBrain(
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("click", map[string]interface{}{"id": 3})}},
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("submit_task_result", map[string]interface{}{"final_report": "ok"})}},
+	)
+	o.Brain = brain2
+	browser.URL = "https://shop.test"
+	browser.Calls = nil
+	o.RunTask(context.Background(), "Кликни")
+	o.Retry.Verify = false
+	o.Brain = agenttest.NewScriptedBrain(
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("click", map[string]interface{}{"id": 3})}},
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("submit_task_result", map[string]interface{}{"final_report": "ok"})}},
+	)
+	again := o.RunTask(context.Background(), "Кликни без проверки")
+	if got := browser.CallLog(); !reflect.DeepEqual(got, []string{"click 3", "click 3 scroll_into_view", "click 3"}) {
+		t.Errorf("Browser calls: %v", got)
+	}
+	if again.History[0].Result != "Success" {
+		t.Errorf("Without verification result must be plain Success, got %q", again.History[0].Result)
+	}
+}
+
+func TestValueMatches(t *testing.T) {
+	cases := []struct {
+		value, text string
+		want        bool
+	}{
+		{"слон", "слон", true},
+		{"слон плюшевый", "слон", true},             // автодополнение
+		{"+7 (999) 123-45-67", "79991234567", true}, // маска телефона
+		{"", "слон", false},
+		{"слово", "слон", false},
+		{"2025-03-14", "14.03.2025", false}, // дата — не номер телефона
+	}
+	for _, tc := range cases {
+		if got := valueMatches(tc.value, tc.text); got != tc.want {
+			t.Errorf("valueMatches(%q, %q) = %v, want %v", tc.value, tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestRunTask_PointerTools(t *testing.T) {
+	brain := agenttest.NewScriptedBrain(
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{
+			call("hover", map[string]interface{}{"id": 1}),
+			call("double_click", map[string]interface{}{"id": 2}),
+			call("right_click", map[string]interface{}{"id": 3}),
+			call("drag", map[string]interface{}{"from_id": 4, "to_id": 5}),
+			call("drag", map[string]interface{}{"from_id": 4}),
+			call("hover", nil),
+		}},
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("submit_task_result", map[string]interface{}{"final_report": "ok"})}},
+	)
+	browser := agenttest.NewBrowser("about:blank", shopPages())
+	o := newTestOrchestrator(browser, brain)
+
+	result := o.RunTask(context.Background(), "Перетащи карточку")
+
+	if got := browser.CallLog(); !reflect.DeepEqual(got, []string{"hover 1", "double_click 2", "right_click 3", "drag 4 5"}) {
+		t.Errorf("Browser calls: %v", got)
+	}
+	if result.History[4].Result != "Error: missing 'from_id' or 'to_id'" || result.History[5].Result != "Error: missing or invalid 'id'" {
+		t.Errorf("Unexpected errors: %q, %q", result.History[4].Result, result.History[5].Result)
+	}
+}
+
+func TestRunTask_WaitsAfterActionsAndWaitFor(t *testing.T) {
+	brain := agenttest.NewScriptedBrain(
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{
+			call("navigate", map[string]interface{}{"url": "https://shop.test/search?q=слон"}),
+			call("wait_for", map[string]interface{}{"condition": "text", "text": "слон плюшевый"}),
+			call("wait_for", map[string]interface{}{"condition": "text_gone", "text": "Загрузка", "timeout": 90}),
+			call("wait_for", map[string]interface{}{"condition": "text", "text": "Доставка", "timeout": 2}),
+			call("wait_for", map[string]interface{}{"condition": "network_idle"}),
+			call("wait_for", map[string]interface{}{"condition": "text"}),
+			call("wait_for", map[string]interface{}{"condition": "sleep"}),
+			call("read_text", map[string]interface{}{"id": 5}),
+		}},
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("submit_task_result", map[string]interface{}{"final_report": "ok"})}},
+	)
+	browser := agenttest.NewBrowser("about:blank", shopPages())
+	o := newTestOrchestrator(browser, brain)
+
+	result := o.RunTask(context.Background(), "Дождись результатов")
+
+	want := []string{
+		"Success",
+		`Success: text "слон плюшевый" appeared`,
+		`Success: text "Загрузка" is gone`,
+		"Error: text not reached within 2s",
+		"Success: network_idle reached",
+		`Error: condition "text" needs 'text'`,
+		`Error: unknown condition "sleep" (use text, text_gone, network_idle, dom_quiet or navigation)`,
+		"Text of element 5: Слон плюшевый, 100 монет",
+	}
+	for i, w := range want {
+		if result.History[i].Result != w {
+			t.Errorf("History[%d]: got %q, want %q", i, result.History[i].Result, w)
+		}
+	}
+
+	// navigate ждет по своей политике, wait_for — с таймаутом модели (не больше 30 с);
+	// read_text и отклоненные вызовы не ждут ничего
+	waits := browser.Waits
+	if len(waits) != 5 {
+		t.Fatalf("Expected 5 waits, got %d: %+v", len(waits), waits)
+	}
+	if !reflect.DeepEqual(waits[0], o.Waits["navigate"]) {
+		t.Errorf("navigate waited %+v", waits[0])
+	}
+	if waits[1].Timeout != 10*time.Second || waits[2].Timeout != 30*time.Second || waits[3].Timeout != 2*time.Second {
+		t.Errorf("wait_for timeouts: %v, %v, %v", waits[1].Timeout, waits[2].Timeout, waits[3].Timeout)
+	}
+
+	// Ожидание, которое не дождалось, после действия — не ошибка
+	brain2 := agenttest.NewScriptedBrain(
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("scroll", map[string]interface{}{"direction": "down"})}},
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("submit_task_result", map[string]interface{}{"final_report": "ok"})}},
+	)
+	o.Brain = brain2
+	browser.Waits = nil
+	browser.Fail["wait"] = errors.New("dom_quiet not reached within 1s")
+	o.Out = nil
+
+	events, unsubscribe := o.Events.Subscribe(16)
+	result = o.RunTask(context.Background(), "Прокрути")
+	unsubscribe()
+	if result.Status != entity.TaskCompleted || result.History[0].Result != "Success" {
+		t.Errorf("Unexpected result: %s, %q", result.Status, result.History[0].Result)
+	}
+	// ...но видно в событиях шага, а не только в консоли
+	var timeouts []Event
+	for e := range events {
+		if e.Type == EventWaitTimeout {
+			timeouts = append(timeouts, e)
+		}
+	}
+	if len(timeouts) != 1 || timeouts[0].Step != 1 || timeouts[0].Message != "После scroll: dom_quiet not reached within 1s, продолжаю" {
+		t.Errorf("Unexpected wait_timeout events: %+v", timeouts)
+	}
+	if len(browser.Waits) != 1 || !reflect.DeepEqual(browser.Waits[0], o.Waits["scroll"]) {
+		t.Errorf("scroll waited %+v", browser.Waits)
+	}
+
+	// Клик ждет один раз: проверка клика дожидается реакции сама, а цикл после нее — нет
+	delete(browser.Fail, "wait")
+	for _, verify := range []bool{true, false} {
+		o.Retry.Verify = verify
+		o.Brain = agenttest.NewScriptedBrain(
+			agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("click", map[string]interface{}{"id": 5})}},
+			agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("submit_task_result", map[string]interface{}{"final_report": "ok"})}},
+		)
+		browser.Waits = nil
+		o.RunTask(context.Background(), "Кликни")
+		if len(browser.Waits) != 1 || !reflect.DeepEqual(browser.Waits[0], o.Waits["click"]) {
+			t.Errorf("verify=%v: click waited %+v", verify, browser.Waits)
+		}
+	}
+}
+
+func TestRunTask_FormTools(t *testing.T) {
+	brain := agenttest.NewScriptedBrain(
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{
+			call("select_option", map[string]interface{}{"id": 1, "option": "Казань"}),
+			call("set_value", map[string]interface{}{"id": 2, "value": "2025-03-14"}),
+			call("upload_file", map[string]interface{}{"id": 5, "file": "../etc/passwd"}),
+			call("select_option", map[string]interface{}{"id": 1}),
+		}},
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("submit_task_result", map[string]interface{}{"final_report": "ok"})}},
+	)
+	browser := agenttest.NewBrowser("about:blank", shopPages())
+	o := newTestOrchestrator(browser, brain)
+	browser.Fail["upload_file"] = errors.New(`file "../etc/passwd" is outside UPLOAD_DIR`)
+
+	result := o.RunTask(context.Background(), "Заполни форму")
+
+	want := []string{
+		`Selected "Казань" in element 1`,
+		`Element 2 value is now "2025-03-14"`,
+		`Error: file "../etc/passwd" is outside UPLOAD_DIR`,
+		"Error: missing 'id' or 'option'",
+	}
+	for i, w := range want {
+		if result.History[i].Result != w {
+			t.Errorf("History[%d]: got %q, want %q", i, result.History[i].Result, w)
+		}
+	}
+	if got := browser.CallLog(); !reflect.DeepEqual(got, []string{"select_option 1 Казань", "set_value 2 2025-03-14", "upload_file 5 ../etc/passwd"}) {
+		t.Errorf("Browser calls: %v", got)
+	}
+}
+
+func TestRunTask_ExtractedDataInResult(t *testing.T) {
+	pages := shopPages()
+	pages["https://shop.test/search?q=слон"].Data = map[int]*entity.ExtractedData{
+		5: {
+			Kind:    "list",
+			Columns: []string{"text", "link"},
+			Rows: []map[string]string{
+				{"text": "Слон плюшевый 100 монет", "link": "https://shop.test/1"},
+				{"text": "Слон фарфоровый 250 монет", "link": "https://shop.test/2"},
+			},
+			Total: 2,
+		},
+	}
+	brain := agenttest.NewScriptedBrain(
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{
+			call("navigate", map[string]interface{}{"url": "https://shop.test/search?q=слон"}),
+		}},
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{
+			call("extract_list", map[string]interface{}{"id": 5, "max_rows": 10}),
+			call("extract_table", map[string]interface{}{}),
+		}},
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("submit_task_result", map[string]interface{}{"final_report": "2 слона"})}},
+		// Вторая задача
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("submit_task_result", map[string]interface{}{"final_report": "ok"})}},
+	)
+	browser := agenttest.NewBrowser("about:blank", pages)
+	o := newTestOrchestrator(browser, brain)
+
+	result := o.RunTask(context.Background(), "Собери слонов с ценами")
+
+	if got := browser.CallLog()[1:]; !reflect.DeepEqual(got, []string{"extract_list 5 10", "extract_table 0 0"}) {
+		t.Errorf("Browser calls: %v", got)
+	}
+	// Строки видны модели в истории, а ошибка (таблицы нет) — как обычно
+	if !strings.Contains(result.History[1].Result, `"text":"Слон фарфоровый 250 монет"`) {
+		t.Errorf("Rows missing from history: %q", result.History[1].Result)
+	}
+	if !strings.HasPrefix(result.History[2].Result, "Error:") {
+		t.Errorf("Expected error for missing table, got %q", result.History[2].Result)
+	}
+
+	if len(result.Extracted) != 1 {
+		t.Fatalf("Expected 1 extraction in result, got %d", len(result.Extracted))
+	}
+	if got := result.Extracted[0]; got.URL != "https://shop.test/search?q=слон" || len(got.Rows) != 2 {
+		t.Errorf("Unexpected extraction: %+v", got)
+	}
+
+	// Следующая задача начинает с пустого списка
+	if again := o.RunTask(context.Background(), "Ничего"); len(again.Extracted) != 0 {
+		t.Errorf("Extracted data leaked into next task: %v", again.Extracted)
+	}
+}
+
+func TestRunTaskWithSchema_RejectsAndAcceptsOutput(t *testing.T) {
+	resultSchema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"price": map[string]any{"type": "number"},
+		},
+		"required": []any{"price"},
+	}
+	brain := agenttest.NewScriptedBrain(
+		// Цена строкой — не по схеме, задача не завершается
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("submit_task_result", map[string]interface{}{
+			"final_report": "Слон стоит 100 монет",
+			"result":       map[string]interface{}{"price": "100 монет"},
+		})}},
+		// Вложенный объект строкой с JSON тоже принимается
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("submit_task_result", map[string]interface{}{
+			"final_report": "Слон стоит 100 монет",
+			"result":       `{"price": 100}`,
+		})}},
+	)
+	browser := agenttest.NewBrowser("about:blank", shopPages())
+	o := newTestOrchestrator(browser, brain)
+
+	result := o.RunTaskWithSchema(context.Background(), "Узнай цену слона", resultSchema)
+
+	if result.Status != entity.TaskCompleted || result.Steps != 2 {
+		t.Fatalf("Expected completed in 2 steps, got %s in %d", result.Status, result.Steps)
+	}
+	if !reflect.DeepEqual(brain.Schema, resultSchema) {
+		t.Errorf("Brain did not get the schema: %v", brain.Schema)
+	}
+	if r := result.History[0].Result; !strings.HasPrefix(r, "Error:") || !strings.Contains(r, "$.price: expected number, got string") {
+		t.Errorf("Expected schema error in history, got %q", r)
+	}
+	if string(result.Output) != `{"price":100}` {
+		t.Errorf("Unexpected output: %s", result.Output)
+	}
+
+	// Строка, похожая на число, по строковой схеме остается строкой
+	orderSchema := map[string]any{"type": "string"}
+	order := newTestOrchestrator(browser, agenttest.NewScriptedBrain(
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("submit_task_result", map[string]interface{}{
+			"final_report": "Заказ 12345", "result": "12345",
+		})}},
+	))
+	if r := order.RunTaskWithSchema(context.Background(), "Номер заказа", orderSchema); r.Status != entity.TaskCompleted || string(r.Output) != `"12345"` {
+		t.Errorf("Order ID: status %s, output %s", r.Status, r.Output)
+	}
+
+	// Без схемы result не нужен и не проверяется
+	plain := newTestOrchestrator(browser, agenttest.NewScriptedBrain(
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("submit_task_result", map[string]interface{}{"final_report": "ok"})}},
+	))
+	if r := plain.RunTask(context.Background(), "Без схемы"); r.Status != entity.TaskCompleted || r.Output != nil {
+		t.Errorf("Plain task: status %s, output %s", r.Status, r.Output)
+	}
+}
+
+func TestRunTask_Cancelled(t *testing.T) {
+	brain := agenttest.NewScriptedBrain(
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("scroll", map[string]interface{}{"direction": "down"})}},
+	)
+	browser := agenttest.NewBrowser("about:blank", shopPages())
+	o := newTestOrchestrator(browser, brain)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result := o.RunTask(ctx, "Не успеешь")
+
+	if result.Status != entity.TaskCancelled {
+		t.Fatalf("Expected cancelled, got %s", result.Status)
+	}
+	if len(browser.CallLog()) != 0 {
+		t.Errorf("No actions expected after cancel, got %v", browser.CallLog())
+	}
+}
+
+func TestRunTask_Events(t *testing.T) {
+	brain := agenttest.NewScriptedBrain(
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{call("submit_task_result", map[string]interface{}{"final_report": "ok"})}},
+	)
+	browser := agenttest.NewBrowser("about:blank", shopPages())
+	o := newTestOrchestrator(browser, brain)
+
+	events, unsubscribe := o.Events.Subscribe(16)
+	o.RunTask(context.Background(), "Событийная задача")
+	unsubscribe()
+
+	var got []EventType
+	for e := range events {
+		got = append(got, e.Type)
+	}
+	want := []EventType{
+		EventTaskStarted, EventStepStarted, EventObservation,
+		EventToolCalls, EventToolExecuted, EventTaskFinished,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Events:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestRunTask_MemoryPersistence(t *testing.T) {
+	remember := func() *agenttest.ScriptedBrain {
+		return agenttest.NewScriptedBrain(agenttest.ScriptStep{Calls: []agenttest.ScriptCall{
+			call("memorize", map[string]interface{}{"info": "Пароль от Wi-Fi: 1234"}),
+			call("submit_task_result", map[string]interface{}{"final_report": "ok"}),
+		}})
+	}
+	browser := agenttest.NewBrowser("about:blank", shopPages())
+
+	o := newTestOrchestrator(browser, remember())
+	o.RunTask(context.Background(), "Запомни")
+
+	// Без PersistMemory следующая задача начинает с чистой памятью
+	brain := agenttest.NewScriptedBrain(agenttest.ScriptStep{Calls: []agenttest.ScriptCall{
+		call("submit_task_result", map[string]interface{}{"final_report": "ok"}),
+	}})
+	o.Brain = brain
+	o.RunTask(context.Background(), "Вспомни")
+	if len(brain.Memory) != 0 {
+		t.Errorf("Memory must be cleared between tasks, got %v", brain.Memory)
+	}
+
+	// С PersistMemory факт доходит до мозга в следующей задаче
+	o.PersistMemory = true
+	o.Brain = remember()
+	o.RunTask(context.Background(), "Запомни")
+	brain = agenttest.NewScriptedBrain(agenttest.ScriptStep{Calls: []agenttest.ScriptCall{
+		call("submit_task_result", map[string]interface{}{"final_report": "ok"}),
+	}})
+	o.Brain = brain
+	o.RunTask(context.Background(), "Вспомни")
+	if !reflect.DeepEqual(brain.Memory, []string{"Пароль от Wi-Fi: 1234"}) {
+		t.Errorf("Persistent memory lost, got %v", brain.Memory)
+	}
+}
@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"browser-agent/internal/entity"
+)
+
+// EventType — что именно произошло в цикле RunTask
+type EventType string
+
+const (
+	EventTaskStarted  EventType = "task_started"
+	EventStepStarted  EventType = "step_started"
+	EventObservation  EventType = "observation"   // Браузер снял состояние страницы
+	EventToolCalls    EventType = "tool_calls"    // Мозг предложил действия (или ошибся)
+	EventToolExecuted EventType = "tool_executed" // Действие выполнено, результат записан в историю
+	EventActionRetry  EventType = "action_retry"  // click/type не дошли до элемента, пробуется запасной способ
+	EventWaitTimeout  EventType = "wait_timeout"  // Страница не успокоилась после действия за время политики
+	EventTaskFinished EventType = "task_finished"
+)
+
+// Event — структурированное событие оркестратора для UI и API-сервера.
+// Заполнены только поля, относящиеся к Type.
+type Event struct {
+	Type EventType `json:"type"`
+	Task string    `json:"task"`
+	Step int       `json:"step,omitempty"`
+	Time time.Time `json:"time"`
+
+	URL   string `json:"url,omitempty"`   // observation
+	Title string `json:"title,omitempty"` // observation
+
+	Calls []entity.ToolCall `json:"calls,omitempty"` // tool_calls
+	Error string            `json:"error,omitempty"` // tool_calls: ошибка LLM
+
+	Action  *entity.ActionRecord `json:"action,omitempty"`  // tool_executed
+	Message string               `json:"message,omitempty"` // action_retry, wait_timeout
+	Result  *entity.TaskResult   `json:"result,omitempty"`  // task_finished
+}
+
+// EventBus раздает события всем подписчикам.
+// Publish никогда не блокирует оркестратора: если подписчик не успевает
+// вычитывать свой буфер, событие для него теряется.
+type EventBus struct {
+	mu     sync.RWMutex
+	subs   map[int]chan Event
+	nextID int
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[int]chan Event)}
+}
+
+// Subscribe возвращает канал событий и функцию отписки (она закрывает канал)
+func (b *EventBus) Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = ch
+	b.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
+
+func (b *EventBus) Publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			log.Printf("⚠️ Подписчик не успевает, событие %s потеряно", e.Type)
+		}
+	}
+}
+
+// emit публикует событие и печатает его в консоль (если задан Out)
+func (o *Orchestrator) emit(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if o.Events != nil {
+		o.Events.Publish(e)
+	}
+	if o.Out != nil {
+		printEvent(o.Out, e)
+	}
+}
+
+// notice — событие текущего шага изнутри действия (повтор click/type, ожидание)
+func (o *Orchestrator) notice(t EventType, format string, args ...any) {
+	o.emit(Event{Type: t, Task: o.task, Step: o.step, Message: fmt.Sprintf(format, args...)})
+}
+
+// printEvent — человекочитаемый вывод хода задачи в терминал
+func printEvent(w io.Writer, e Event) {
+	switch e.Type {
+	case EventTaskStarted:
+		fmt.Fprintf(w, "🎯 Принята задача: %s\n", e.Task)
+
+	case EventStepStarted:
+		fmt.Fprintf(w, "\n--- STEP %d ---\n", e.Step)
+
+	case EventObservation:
+		fmt.Fprintf(w, "🌍 URL: %s | Title: %s\n", e.URL, e.Title)
+
+	case EventToolCalls:
+		if e.Error != "" {
+			return // Ошибка LLM уже ушла в log
+		}
+		if len(e.Calls) == 0 {
+			fmt.Fprintln(w, "🤔 Агент задумался (нет действий)...")
+			return
+		}
+		// Мысль общая на всю пачку действий
+		fmt.Fprintf(w, "💭 Reasoning: %s\n", e.Calls[0].Reasoning)
+		for _, call := range e.Calls {
+			fmt.Fprintf(w, "⚡ Action: %s %+v\n", call.Name, call.Args)
+		}
+
+	case EventToolExecuted:
+		fmt.Fprintf(w, "✅ Result (%s): %s\n", e.Action.Action, e.Action.Result)
+
+	case EventActionRetry:
+		fmt.Fprintf(w, "🔁 %s\n", e.Message)
+
+	case EventWaitTimeout:
+		fmt.Fprintf(w, "⏳ %s\n", e.Message)
+
+	case EventTaskFinished:
+		switch e.Result.Status {
+		case entity.TaskCompleted:
+			fmt.Fprintln(w, "\n🎉 ЗАДАЧА ВЫПОЛНЕНА! Готов к следующей.")
+		case entity.TaskStepLimit:
+			fmt.Fprintln(w, "⚠️ Превышен лимит шагов. Остановка.")
+		case entity.TaskCancelled:
+			fmt.Fprintf(w, "🛑 Задача прервана: %s\n", e.Result.Error)
+		}
+	}
+}
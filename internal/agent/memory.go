@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"strings"
+	"sync"
+)
+
+// Memory — факты, сохраненные агентом через инструмент memorize.
+// Мозг получает их отдельной секцией промпта на каждом шаге, поэтому они
+// не теряются вместе со старой историей действий.
+type Memory struct {
+	mu    sync.RWMutex
+	facts []string
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Add сохраняет факт. Пустые строки и точные повторы игнорируются.
+func (m *Memory) Add(fact string) bool {
+	fact = strings.TrimSpace(fact)
+	if fact == "" {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, f := range m.facts {
+		if f == fact {
+			return false
+		}
+	}
+	m.facts = append(m.facts, fact)
+	return true
+}
+
+// Facts возвращает копию всех фактов в порядке сохранения
+func (m *Memory) Facts() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	facts := make([]string, len(m.facts))
+	copy(facts, m.facts)
+	return facts
+}
+
+func (m *Memory) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.facts)
+}
+
+func (m *Memory) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.facts = nil
+}
@@ -0,0 +1,191 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"browser-agent/internal/entity"
+)
+
+// RetryPolicy — проверка click и type после выполнения и повторы другими способами.
+// "Success" от браузера значит только, что событие отправлено: клик мог попасть
+// в оверлей, а текст — уйти мимо поля.
+type RetryPolicy struct {
+	Verify     bool // Проверять результат click и type
+	MaxRetries int  // Сколько запасных способов пробовать после основного
+}
+
+// DefaultRetryPolicy — проверка включена, до двух запасных способов
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{Verify: true, MaxRetries: 2}
+}
+
+// Запасные способы — в порядке от самого похожего на действия пользователя
+var (
+	clickStrategies = []entity.ActionStrategy{entity.StrategyDefault, entity.StrategyScrollIntoView, entity.StrategyJSClick}
+	typeStrategies  = []entity.ActionStrategy{entity.StrategyDefault, entity.StrategyFocusType, entity.StrategyJSValue}
+)
+
+// strategies — основной способ и не больше MaxRetries запасных
+func (o *Orchestrator) strategies(all []entity.ActionStrategy) []entity.ActionStrategy {
+	n := 1 + o.Retry.MaxRetries
+	if n > len(all) {
+		n = len(all)
+	}
+	if n < 1 {
+		n = 1
+	}
+	return all[:n]
+}
+
+// verifiedClick кликает и проверяет видимый эффект: сменился URL или вкладка,
+// изменился DOM, переключилось состояние элемента. Следующий способ пробуется,
+// только если клик не состоялся (ошибка) или и после клика, который прокручивает
+// к элементу, его центр закрыт другим элементом (оверлей). Элемент за экраном до
+// клика — не повод повторять. Клик, который прошел, но ничего видимого не
+// сделал, не повторяется: "В корзину", "Оплатить" или лайк сработали бы дважды,
+// а кнопка может работать незаметно (ответ сервера медленный, эффект вне DOM).
+// Модель увидит пометку unverified и сама решит, что делать.
+func (o *Orchestrator) verifiedClick(ctx context.Context, id int) (string, error) {
+	if !o.Retry.Verify {
+		return "Success", o.Browser.Click(ctx, id)
+	}
+
+	var tried []string
+	var lastErr error
+	clicked := false
+	strategies := o.strategies(clickStrategies)
+	for i, strategy := range strategies {
+		before, probeErr := o.Browser.Probe(ctx, id)
+		tried = append(tried, string(strategy))
+
+		if err := o.Browser.ClickWith(ctx, id, strategy); err != nil {
+			lastErr = err
+			if ctx.Err() != nil {
+				return "", err
+			}
+			continue
+		}
+		clicked = true
+
+		// Страница реагирует не мгновенно — ждем по политике клика
+		// (за проверенный клик цикл run уже не ждет)
+		if err := o.settle(ctx, "click"); err != nil {
+			return "", err
+		}
+		// Сравнивать не с чем — не повторяем вслепую
+		if probeErr != nil {
+			return "Success", nil
+		}
+		after, err := o.Browser.Probe(ctx, id)
+		if effect := clickEffect(before, after, err); effect != "" {
+			return verified(effect, tried), nil
+		}
+		if !after.Found || !after.Obscured {
+			break
+		}
+		if i < len(strategies)-1 {
+			o.notice(EventActionRetry, "Клик по %d (%s) не дошел до элемента: он перекрыт", id, strategy)
+		}
+	}
+
+	if !clicked {
+		return "", lastErr
+	}
+	return fmt.Sprintf("Success (unverified: no visible effect — URL, page and element state unchanged; tried %s)", strings.Join(tried, ", ")), nil
+}
+
+// verifiedType вводит текст и проверяет, что он оказался в поле; иначе пробует
+// запасные способы. Текст, который так и не попал в поле, — ошибка.
+func (o *Orchestrator) verifiedType(ctx context.Context, id int, text string) (string, error) {
+	if !o.Retry.Verify {
+		return "Success", o.Browser.Type(ctx, id, text)
+	}
+
+	var tried []string
+	var lastErr error
+	actual, typed := "", false
+	strategies := o.strategies(typeStrategies)
+	for i, strategy := range strategies {
+		tried = append(tried, string(strategy))
+
+		if err := o.Browser.TypeWith(ctx, id, text, strategy); err != nil {
+			lastErr = err
+			if ctx.Err() != nil {
+				return "", err
+			}
+			continue
+		}
+		typed = true
+
+		after, err := o.Browser.Probe(ctx, id)
+		if err != nil || !after.Found {
+			// Поле пропало или страница перестраивается — проверить нечем
+			return "Success", nil
+		}
+		if valueMatches(after.Value, text) {
+			return verified("field value matches", tried), nil
+		}
+		actual = after.Value
+		if i < len(strategies)-1 {
+			o.notice(EventActionRetry, "Ввод в %d (%s) не сработал: в поле %q", id, strategy, actual)
+		}
+	}
+
+	if !typed {
+		return "", lastErr
+	}
+	return "", fmt.Errorf("text did not stick: field value is %q (tried %s)", actual, strings.Join(tried, ", "))
+}
+
+// clickEffect — что изменил клик; "" — ничего
+func clickEffect(before, after *entity.ActionProbe, err error) string {
+	switch {
+	case err != nil:
+		// Страница перезагружается или вкладка закрылась — клик явно сработал
+		return "page is reloading"
+	case after.TargetID != before.TargetID:
+		return "switched to a new tab"
+	case after.URL != before.URL:
+		return "URL changed"
+	case before.Found && !after.Found:
+		return "element disappeared"
+	case after.State != before.State || after.Value != before.Value:
+		return "element state changed"
+	case after.Mutations != before.Mutations:
+		return "page changed"
+	}
+	return ""
+}
+
+// valueMatches — текст в поле: точно, с дописанным автодополнением или
+// с маской (телефон "+7 (999) 123-45-67" вместо "79991234567")
+func valueMatches(value, text string) bool {
+	value, text = strings.TrimSpace(value), strings.TrimSpace(text)
+	if value == text || (text != "" && strings.Contains(value, text)) {
+		return true
+	}
+	digits := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, s)
+	}
+	// Сравнение по цифрам — только для текста из цифр и знаков номера
+	phoneLike := strings.IndexFunc(text, func(r rune) bool {
+		return !unicode.IsDigit(r) && !strings.ContainsRune(" +-().", r)
+	}) < 0
+	d := digits(text)
+	return phoneLike && d != "" && digits(value) == d
+}
+
+func verified(effect string, tried []string) string {
+	if len(tried) == 1 {
+		return "Success (verified: " + effect + ")"
+	}
+	return fmt.Sprintf("Success (verified after %s: %s)", tried[len(tried)-1], effect)
+}
@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"browser-agent/internal/entity"
+)
+
+const (
+	defaultWaitFor = 10 * time.Second // wait_for без timeout
+	maxWaitFor     = 30 * time.Second
+)
+
+// DefaultWaitPolicies — чего ждать после каждого действия вместо фиксированных
+// пауз: быстрая страница отпускает сразу, медленная — не дольше Timeout.
+// Действия без политики (read_text, memorize, extract_*) не ждут ничего.
+func DefaultWaitPolicies() map[string]entity.WaitPolicy {
+	// Клик может быть переходом, отправкой формы или подгрузкой через fetch
+	settle := entity.WaitPolicy{
+		Conditions: []entity.WaitCondition{entity.WaitNavigation, entity.WaitNetworkIdle, entity.WaitDOMQuiet},
+		Quiet:      300 * time.Millisecond,
+		Timeout:    5 * time.Second,
+	}
+	// Меню, подсказки, автодополнение, ленивая подгрузка при прокрутке
+	react := entity.WaitPolicy{
+		Conditions: []entity.WaitCondition{entity.WaitDOMQuiet},
+		Quiet:      150 * time.Millisecond,
+		Timeout:    time.Second,
+	}
+	return map[string]entity.WaitPolicy{
+		"click":         settle,
+		"double_click":  settle,
+		"right_click":   react,
+		"drag":          settle,
+		"press":         settle,
+		"go_back":       settle,
+		"close_tab":     settle,
+		"select_option": settle,
+		"upload_file":   settle,
+		"hover":         react,
+		"scroll":        react,
+		"type":          react,
+		"set_value":     react,
+		"navigate": {
+			Conditions: []entity.WaitCondition{entity.WaitNavigation, entity.WaitNetworkIdle, entity.WaitDOMQuiet},
+			Quiet:      500 * time.Millisecond,
+			Timeout:    10 * time.Second,
+		},
+	}
+}
+
+// settle ждет, пока страница успокоится после действия, по политике из Waits.
+// Не дождались — не ошибка: модель увидит страницу как есть на следующем шаге
+// и при необходимости дождется нужного через wait_for.
+func (o *Orchestrator) settle(ctx context.Context, action string) error {
+	policy, ok := o.Waits[action]
+	if !ok {
+		return ctx.Err()
+	}
+	if err := o.Browser.Wait(ctx, policy); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		o.notice(EventWaitTimeout, "После %s: %v, продолжаю", action, err)
+	}
+	return nil
+}
+
+// settlesItself — действие уже дождалось реакции страницы: проверенный клик
+// ждет перед тем, как сравнить состояние, и второй раз ждать незачем
+func (o *Orchestrator) settlesItself(action string) bool {
+	return action == "click" && o.Retry.Verify
+}
+
+// waitFor — инструмент wait_for: одно условие с таймаутом от модели
+func (o *Orchestrator) waitFor(ctx context.Context, args map[string]interface{}) (string, error) {
+	condition, ok := getString(args, "condition")
+	if !ok {
+		return "", fmt.Errorf("missing 'condition'")
+	}
+	policy := entity.WaitPolicy{
+		Conditions: []entity.WaitCondition{entity.WaitCondition(condition)},
+		Timeout:    defaultWaitFor,
+	}
+	if seconds, ok := getInt(args, "timeout"); ok && seconds > 0 {
+		policy.Timeout = time.Duration(seconds) * time.Second
+		if policy.Timeout > maxWaitFor {
+			policy.Timeout = maxWaitFor
+		}
+	}
+
+	var output string
+	switch policy.Conditions[0] {
+	case entity.WaitText, entity.WaitTextGone:
+		text, ok := getString(args, "text")
+		if !ok || text == "" {
+			return "", fmt.Errorf("condition %q needs 'text'", condition)
+		}
+		policy.Text = text
+		output = fmt.Sprintf("Success: text %q appeared", text)
+		if policy.Conditions[0] == entity.WaitTextGone {
+			output = fmt.Sprintf("Success: text %q is gone", text)
+		}
+	case entity.WaitNavigation, entity.WaitNetworkIdle, entity.WaitDOMQuiet:
+		output = fmt.Sprintf("Success: %s reached", condition)
+	default:
+		return "", fmt.Errorf("unknown condition %q (use text, text_gone, network_idle, dom_quiet or navigation)", condition)
+	}
+
+	if err := o.Browser.Wait(ctx, policy); err != nil {
+		return "", err
+	}
+	return output, nil
+}
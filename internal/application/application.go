@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
 
 	"browser-agent/internal/agent"
@@ -15,34 +16,11 @@ import (
 )
 
 func Run(ctx context.Context) error {
-	// 1. Загружаем конфигурацию
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		// LoadConfig возвращает понятную ошибку, если нет ключа
-		return fmt.Errorf("initialization failed: %w", err)
-	}
-
-	log.Println("🚀 Инициализация системы...")
-	log.Printf("🔧 Конфигурация: Model=%s, BaseURL=%s", cfg.Model, cfg.Url)
-
-	// 2. Запускаем браузер (Persistent Session)
-	log.Println("🔌 Запускаем браузер...")
-	// false = headless выключен (мы видим браузер), true = скрытый режим
-	browserSvc, err := browser.NewBrowserService(ctx, false)
+	orchestrator, cleanup, err := bootstrap(ctx, false)
 	if err != nil {
-		return fmt.Errorf("browser launch error: %w", err)
+		return err
 	}
-	defer browserSvc.Close()
-
-	// 3. Поднимаем Мозг (LLM) используя данные из конфига
-	llmClient := llm.New(
-		cfg.APIKey,
-		cfg.Model,
-		cfg.Url,
-	)
-
-	// 4. Создаем Оркестратора (Агента)
-	orchestrator := agent.New(browserSvc, llmClient)
+	defer cleanup()
 
 	// 5. Запускаем REPL цикл (Read-Eval-Print Loop)
 	reader := bufio.NewReader(os.Stdin)
@@ -50,6 +28,7 @@ func Run(ctx context.Context) error {
 	fmt.Println("\n==================================================")
 	fmt.Println("🤖 AGENT ONLINE. Браузер готов к командам.")
 	fmt.Println("   (Введите 'exit', 'quit' или Ctrl+C для выхода)")
+	fmt.Println("   ('/memory' — показать память агента, '/memory clear' — очистить)")
 	fmt.Println("==================================================")
 
 	for {
@@ -75,14 +54,86 @@ func Run(ctx context.Context) error {
 			log.Println("👋 Завершение работы...")
 			break
 		}
+		if strings.HasPrefix(task, "/memory") {
+			memoryCommand(orchestrator.Memory, strings.TrimSpace(strings.TrimPrefix(task, "/memory")))
+			continue
+		}
 
 		log.Printf("🏁 [START] Выполняю задачу: '%s'", task)
 
+		// Ctrl+C во время задачи прерывает только её, а не весь REPL
+		taskCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+
 		// Запускаем задачу через Агента
-		orchestrator.RunTask(task)
+		result := orchestrator.RunTask(taskCtx, task)
+		stop()
 
-		log.Println("✨ Задача завершена (или прервана). Готов к следующей.")
+		log.Printf("✨ Задача завершена [%s] за %d шагов. Готов к следующей.", result.Status, result.Steps)
 	}
 
 	return nil
 }
+
+// memoryCommand — REPL-команды '/memory' и '/memory clear'
+func memoryCommand(memory *agent.Memory, arg string) {
+	switch arg {
+	case "":
+		facts := memory.Facts()
+		if len(facts) == 0 {
+			fmt.Println("🧠 Память пуста.")
+			return
+		}
+		fmt.Printf("🧠 В памяти %d фактов:\n", len(facts))
+		for i, fact := range facts {
+			fmt.Printf("  %d. %s\n", i+1, fact)
+		}
+	case "clear":
+		memory.Clear()
+		fmt.Println("🧹 Память очищена.")
+	default:
+		fmt.Println("❌ Неизвестная команда. Используйте '/memory' или '/memory clear'.")
+	}
+}
+
+// bootstrap поднимает конфиг, браузер, LLM и оркестратора.
+// cleanup закрывает браузер — его нужно вызвать через defer.
+func bootstrap(ctx context.Context, headless bool) (*agent.Orchestrator, func(), error) {
+	// 1. Загружаем конфигурацию
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		// LoadConfig возвращает понятную ошибку, если нет ключа
+		return nil, nil, fmt.Errorf("initialization failed: %w", err)
+	}
+
+	log.Println("🚀 Инициализация системы...")
+	log.Printf("🔧 Конфигурация: Provider=%s, Model=%s, BaseURL=%s, TokenBudget=%d, Vision=%t, Observer=%s, TextBudget=%d, UploadDir=%q, VerifyActions=%t, ActionRetries=%d", cfg.Provider, cfg.Model, cfg.Url, cfg.TokenBudget, cfg.Vision, cfg.Observer, cfg.TextBudget, cfg.UploadDir, cfg.VerifyActions, cfg.ActionRetries)
+
+	// 2. Запускаем браузер (Persistent Session)
+	log.Println("🔌 Запускаем браузер...")
+	// false = headless выключен (мы видим браузер), true = скрытый режим
+	browserSvc, err := browser.NewBrowserService(ctx, headless)
+	if err != nil {
+		return nil, nil, fmt.Errorf("browser launch error: %w", err)
+	}
+	browserSvc.Vision = cfg.Vision
+	browserSvc.Observer = cfg.Observer
+	browserSvc.TextBudget = cfg.TextBudget
+	browserSvc.UploadDir = cfg.UploadDir
+
+	// 3. Поднимаем Мозг (LLM) используя данные из конфига
+	provider, err := llm.NewProvider(cfg.Provider, cfg.APIKey, cfg.Url)
+	if err != nil {
+		browserSvc.Close()
+		return nil, nil, fmt.Errorf("llm init error: %w", err)
+	}
+	llmClient := llm.NewWithProvider(provider, cfg.Model)
+	llmClient.TokenBudget = cfg.TokenBudget
+
+	// 4. Создаем Оркестратора (Агента)
+	orchestrator := agent.New(browserSvc, llmClient)
+	orchestrator.PersistMemory = cfg.MemoryPersist
+	orchestrator.Retry.Verify = cfg.VerifyActions
+	orchestrator.Retry.MaxRetries = cfg.ActionRetries
+
+	return orchestrator, browserSvc.Close, nil
+}
@@ -0,0 +1,111 @@
+package application
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"browser-agent/internal/agent"
+	"browser-agent/internal/entity"
+)
+
+// ErrTasksFailed — хотя бы одна задача batch-прогона не завершилась успешно
+var ErrTasksFailed = errors.New("some tasks failed")
+
+// BatchOptions — параметры неинтерактивного запуска
+type BatchOptions struct {
+	Tasks       []string
+	Headless    bool
+	TaskTimeout time.Duration // Дедлайн на одну задачу, 0 — без ограничения
+	Output      io.Writer     // Сюда пишется по одной JSON-строке (TaskResult) на задачу
+
+	// Schema — JSON Schema результата, общая для всех задач прогона (nil — без схемы)
+	Schema map[string]any
+}
+
+// RunBatch выполняет задачи по очереди без REPL и печатает результат каждой в JSONL.
+// Ход выполнения агента уходит в stderr, чтобы stdout можно было отдать в пайплайн.
+func RunBatch(ctx context.Context, opts BatchOptions) error {
+	if len(opts.Tasks) == 0 {
+		return fmt.Errorf("no tasks to run")
+	}
+	if opts.Output == nil {
+		opts.Output = os.Stdout
+	}
+
+	orchestrator, cleanup, err := bootstrap(ctx, opts.Headless)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	orchestrator.Out = os.Stderr
+
+	encoder := json.NewEncoder(opts.Output)
+	failed := 0
+
+	for i, task := range opts.Tasks {
+		// Ctrl+C останавливает весь прогон: оставшиеся задачи не запускаем
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		log.Printf("🏁 [%d/%d] Выполняю задачу: '%s'", i+1, len(opts.Tasks), task)
+		result := runWithTimeout(ctx, orchestrator, task, opts.Schema, opts.TaskTimeout)
+
+		if result.Status != entity.TaskCompleted {
+			failed++
+		}
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("write result: %w", err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d", ErrTasksFailed, failed, len(opts.Tasks))
+	}
+	return nil
+}
+
+func runWithTimeout(ctx context.Context, o *agent.Orchestrator, task string, resultSchema map[string]any, timeout time.Duration) *entity.TaskResult {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return o.RunTaskWithSchema(ctx, task, resultSchema)
+}
+
+// ReadTasks читает задачи из файла: одна задача на строку,
+// пустые строки и строки с '#' пропускаются. Путь "-" означает stdin.
+func ReadTasks(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var tasks []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tasks = append(tasks, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read tasks: %w", err)
+	}
+	return tasks, nil
+}
@@ -0,0 +1,55 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"browser-agent/internal/server"
+)
+
+// ServerOptions — параметры запуска HTTP API
+type ServerOptions struct {
+	Addr     string
+	Headless bool
+}
+
+// RunServer поднимает агента как HTTP/JSON сервис и блокируется до отмены ctx
+func RunServer(ctx context.Context, opts ServerOptions) error {
+	orchestrator, cleanup, err := bootstrap(ctx, opts.Headless)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	srv := server.New(orchestrator)
+	go srv.Run(ctx)
+
+	httpServer := &http.Server{
+		Addr:              opts.Addr,
+		Handler:           srv.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("🌐 API слушает %s", opts.Addr)
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("http server: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		log.Println("👋 Остановка API...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return httpServer.Shutdown(shutdownCtx)
+	}
+}
@@ -5,96 +5,35 @@ import (
 	"fmt"
 	"time"
 
+	"browser-agent/internal/keyboard"
+
 	"github.com/go-rod/rod"
-	"github.com/go-rod/rod/lib/input"
 	"github.com/go-rod/rod/lib/proto"
 )
 
-func (s *BrowserService) Click(id int) error {
-	el, err := s.GetElement(id)
-	if err != nil {
-		return fmt.Errorf("элемент ID %d не найден: %w", id, err)
-	}
-
-	pagesBefore, _ := s.browser.Pages()
-	existingIDs := make(map[string]bool)
-	for _, p := range pagesBefore {
-		info, err := p.Info()
-		if err == nil {
-			existingIDs[string(info.TargetID)] = true
-		}
-	}
-
-	// 2. Подсветка (с таймаутом)
-	highlightCtx, highlightCancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer highlightCancel()
-	_, _ = el.Context(highlightCtx).Eval(HighlightClickScript)
-
-	// 3. Контекст с таймаутом для клика
-	clickCtx, clickCancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer clickCancel()
-
-	elWithTimeout := el.Context(clickCtx)
-
-	// 4. Пытаемся кликнуть
-	err = elWithTimeout.Click(proto.InputMouseButtonLeft, 1)
-
-	// 5. Если ошибка — пробуем JS
-	if err != nil {
-		fmt.Printf("⚠️ Обычный клик не удался (%v), пробую JS...\n", err)
-		jsErr := s.forceClickJS(el)
-		if jsErr != nil {
-			return fmt.Errorf("все методы клика провалились: %w", jsErr)
-		}
-	}
-
-	// 6. Проверяем новую вкладку
-	newPage := s.waitForNewTab(existingIDs, 3*time.Second)
-
-	if newPage != nil {
-		fmt.Printf("🔀 Новая вкладка: %s\n", safeGetURL(newPage))
-		s.activatePage(newPage)
-	} else {
-		s.safeWaitLoad(2 * time.Second)
-	}
-
-	// 7. ⚡ ВАЖНО: Очищаем кэш после клика (DOM изменился!)
-	s.ElementMap = make(map[int]*rod.Element)
-
-	return nil
-}
-
-// forceClickJS — принудительный клик через JavaScript
-func (s *BrowserService) forceClickJS(el *rod.Element) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	_, err := el.Context(ctx).Eval(`() => {
-		this.click();
-		this.dispatchEvent(new MouseEvent('click', {bubbles: true}));
-	}`)
-	return err
+func (s *BrowserService) Click(ctx context.Context, id int) error {
+	return s.mouseClick(ctx, id, proto.InputMouseButtonLeft, 1)
 }
 
-func (s *BrowserService) Type(id int, text string) error {
+func (s *BrowserService) Type(ctx context.Context, id int, text string) error {
 	// ✅ Используем GetElement() вместо прямого доступа к map
-	el, err := s.GetElement(id)
+	el, err := s.GetElement(ctx, id)
 	if err != nil {
 		return fmt.Errorf("элемент ID %d не найден: %w", id, err)
 	}
 
 	// Подсветка
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	highlightCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
-	_, _ = el.Context(ctx).Eval(HighlightTypeScript)
+	_, _ = el.Context(highlightCtx).Eval(HighlightTypeScript)
 
 	// Выделяем весь текст (чтобы заменить)
-	if err := el.SelectAllText(); err != nil {
+	if err := el.Context(ctx).SelectAllText(); err != nil {
 		fmt.Printf("⚠️ Не удалось выделить текст: %v\n", err)
 	}
 
 	// Вводим новый текст
-	if err := el.Input(text); err != nil {
+	if err := el.Context(ctx).Input(text); err != nil {
 		return fmt.Errorf("ошибка ввода текста: %w", err)
 	}
 
@@ -107,15 +46,15 @@ func (s *BrowserService) Type(id int, text string) error {
 // ============================================================
 // READ TEXT — чтение текста из элемента
 // ============================================================
-func (s *BrowserService) ReadText(id int) (string, error) {
+func (s *BrowserService) ReadText(ctx context.Context, id int) (string, error) {
 	// ✅ Используем GetElement()
-	el, err := s.GetElement(id)
+	el, err := s.GetElement(ctx, id)
 	if err != nil {
 		return "", fmt.Errorf("элемент ID %d не найден: %w", id, err)
 	}
 
 	// Подсветка
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 	_, _ = el.Context(ctx).Eval(`() => { this.style.border = "3px dashed orange" }`)
 
@@ -141,7 +80,7 @@ func (s *BrowserService) ReadText(id int) (string, error) {
 // ============================================================
 // SCROLL — прокрутка страницы
 // ============================================================
-func (s *BrowserService) Scroll(direction string) error {
+func (s *BrowserService) Scroll(ctx context.Context, direction string) error {
 	var script string
 	if direction == "down" {
 		script = ScrollDownScript
@@ -149,12 +88,10 @@ func (s *BrowserService) Scroll(direction string) error {
 		script = ScrollUpScript
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	evalCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	_, err := s.CurrentPage.Context(ctx).Eval(script)
-
-	time.Sleep(500 * time.Millisecond)
+	_, err := s.CurrentPage.Context(evalCtx).Eval(script)
 
 	// ⚡ Очищаем кэш — после скролла элементы могут измениться
 	s.ElementMap = make(map[int]*rod.Element)
@@ -165,8 +102,8 @@ func (s *BrowserService) Scroll(direction string) error {
 // ============================================================
 // CLOSE TAB — закрытие вкладки
 // ============================================================
-func (s *BrowserService) CloseTab() error {
-	pages, err := s.browser.Pages()
+func (s *BrowserService) CloseTab(ctx context.Context) error {
+	pages, err := s.browser.Context(ctx).Pages()
 	if err != nil {
 		return err
 	}
@@ -179,13 +116,13 @@ func (s *BrowserService) CloseTab() error {
 	s.CurrentPage.Close()
 
 	// Получаем обновленный список
-	newPages, _ := s.browser.Pages()
+	newPages, _ := s.browser.Context(ctx).Pages()
 	if len(newPages) == 0 {
 		return fmt.Errorf("все вкладки закрыты")
 	}
 
 	lastPage := newPages[len(newPages)-1]
-	s.activatePage(lastPage)
+	s.activatePage(ctx, lastPage)
 
 	// ⚡ Очищаем кэш — другая страница
 	s.ElementMap = make(map[int]*rod.Element)
@@ -197,15 +134,15 @@ func (s *BrowserService) CloseTab() error {
 // ============================================================
 // GO BACK — кнопка "Назад"
 // ============================================================
-func (s *BrowserService) GoBack() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *BrowserService) GoBack(ctx context.Context) error {
+	backCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	if err := s.CurrentPage.Context(ctx).NavigateBack(); err != nil {
+	if err := s.CurrentPage.Context(backCtx).NavigateBack(); err != nil {
 		return err
 	}
 
-	s.safeWaitLoad(3 * time.Second)
+	s.safeWaitLoad(ctx, 3*time.Second)
 
 	// ⚡ Очищаем кэш — другая страница
 	s.ElementMap = make(map[int]*rod.Element)
@@ -216,63 +153,45 @@ func (s *BrowserService) GoBack() error {
 // ============================================================
 // PRESS KEY — нажатие клавиши
 // ============================================================
-func (s *BrowserService) PressKey(keyName string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	// Ждём стабильности (с таймаутом!)
-	_ = s.CurrentPage.Context(ctx).WaitStable(300 * time.Millisecond)
-
-	var k input.Key
-
-	switch keyName {
-	case "enter":
-		k = input.Enter
-	case "escape":
-		k = input.Escape
-	case "tab":
-		k = input.Tab
-	case "backspace":
-		k = input.Backspace
-	case "arrow_down":
-		k = input.ArrowDown
-	case "arrow_up":
-		k = input.ArrowUp
-	case "space":
-		k = input.Space
-	default:
-		return fmt.Errorf("unsupported key: %s", keyName)
-	}
-
-	err := s.CurrentPage.Keyboard.Press(k)
+func (s *BrowserService) PressKey(ctx context.Context, keyName string) error {
+	seq, err := keyboard.Parse(keyName)
 	if err != nil {
 		return err
 	}
 
-	time.Sleep(500 * time.Millisecond)
-
-	// ⚡ Очищаем кэш — DOM мог измениться после Enter и т.д.
-	s.ElementMap = make(map[int]*rod.Element)
-
-	return nil
+	// Enter может отправить форму, Control+Enter — открыть вкладку
+	return s.watchTabs(ctx, func() error {
+		// Модификаторы зажимаются на время нажатия клавиши и отпускаются после
+		page := s.CurrentPage.Context(ctx)
+		for _, chord := range seq {
+			if err := page.KeyActions().Press(chord.Modifiers...).Type(chord.Key).Do(); err != nil {
+				return fmt.Errorf("press %s: %w", chord.Name, err)
+			}
+		}
+		return nil
+	})
 }
 
 // ============================================================
 // NAVIGATE — переход на страницу
 // ============================================================
-func (s *BrowserService) Navigate(url string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+func (s *BrowserService) Navigate(ctx context.Context, url string) error {
+	navCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	err := s.CurrentPage.Context(ctx).Navigate(url)
+	// Запросы новой страницы должны попасть в трекер с самого начала
+	s.trackRequests()
+	err := s.CurrentPage.Context(navCtx).Navigate(url)
 	if err != nil {
 		return err
 	}
 
-	s.safeWaitLoad(5 * time.Second)
+	s.safeWaitLoad(ctx, 5*time.Second)
 
-	// ⚡ Очищаем кэш — новая страница
+	// ⚡ Очищаем кэш — новая страница, нумерация элементов начинается заново
 	s.ElementMap = make(map[int]*rod.Element)
+	s.ids = newIDRegistry()
+	s.lastSnapshot = nil
 
 	return nil
 }
@@ -281,34 +200,7 @@ func (s *BrowserService) Navigate(url string) error {
 // ВСПОМОГАТЕЛЬНЫЕ ФУНКЦИИ (без изменений, но с таймаутами)
 // ============================================================
 
-func (s *BrowserService) waitForNewTab(existingIDs map[string]bool, timeout time.Duration) *rod.Page {
-	deadline := time.After(timeout)
-	ticker := time.NewTicker(300 * time.Millisecond)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-deadline:
-			return nil
-		case <-ticker.C:
-			pages, err := s.browser.Pages()
-			if err != nil {
-				continue
-			}
-			for _, p := range pages {
-				info, err := p.Info()
-				if err != nil {
-					continue
-				}
-				if !existingIDs[string(info.TargetID)] {
-					return p
-				}
-			}
-		}
-	}
-}
-
-func (s *BrowserService) safeWaitLoad(timeout time.Duration) {
+func (s *BrowserService) safeWaitLoad(ctx context.Context, timeout time.Duration) {
 	done := make(chan bool, 1)
 
 	go func() {
@@ -319,35 +211,44 @@ func (s *BrowserService) safeWaitLoad(timeout time.Duration) {
 			done <- true
 		}()
 
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		loadCtx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
-		s.CurrentPage.Context(ctx).WaitLoad()
+		s.CurrentPage.Context(loadCtx).WaitLoad()
 	}()
 
 	select {
 	case <-done:
+	case <-ctx.Done():
 	case <-time.After(timeout + 1*time.Second):
 		fmt.Println("⚠️ Таймаут загрузки страницы, продолжаю...")
 	}
 }
 
-func (s *BrowserService) activatePage(page *rod.Page) {
+func (s *BrowserService) activatePage(ctx context.Context, page *rod.Page) {
 	defer func() {
 		if r := recover(); r != nil {
 			fmt.Printf("⚠️ Ошибка активации вкладки: %v\n", r)
 		}
 	}()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	activateCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	page.Context(ctx).Activate()
+	page.Context(activateCtx).Activate()
 	s.CurrentPage = page
 
 	// ⚡ Очищаем кэш — другая страница
 	s.ElementMap = make(map[int]*rod.Element)
 
-	s.safeWaitLoad(3 * time.Second)
+	s.safeWaitLoad(ctx, 3*time.Second)
+}
+
+// sleepCtx — пауза, которая обрывается при отмене ctx
+func sleepCtx(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
 }
 
 func safeGetURL(page *rod.Page) string {
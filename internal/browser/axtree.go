@@ -0,0 +1,256 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Наблюдатели для Config.Observer
+const (
+	ObserverJS = "js" // ObserveElementsScript: эвристики по тегам, классам и cursor: pointer
+	ObserverAX = "ax" // Дерево доступности Chrome (Accessibility.getFullAXTree)
+)
+
+const (
+	// axMaxItems — предохранитель, как MAX_ITEMS у JS-сканера; обычный лимит
+	// (maxElements) применяет Observe
+	axMaxItems = 3000
+	// axObjectGroup — группа JS-объектов узлов, чтобы освобождать их пачкой
+	axObjectGroup = "agent-ax"
+)
+
+// axRoles — интерактивные роли и то, как они выглядят в DOMSummary. Нативные
+// <select>, дата, ползунок и файл получают строку formControl после регистрации
+// ([CHOOSE], [DATE], [RANGE], [FILE]) — как у JS-сканера.
+var axRoles = map[string]string{
+	"textbox":          "input",
+	"searchbox":        "input",
+	"combobox":         "input",
+	"spinbutton":       "input",
+	"slider":           "input",
+	"date":             "input",
+	"dateTime":         "input",
+	"inputTime":        "input",
+	"checkbox":         "checkbox",
+	"radio":            "checkbox",
+	"switch":           "checkbox",
+	"menuitemcheckbox": "checkbox",
+	"menuitemradio":    "checkbox",
+	"link":             "link",
+	"button":           "button",
+	"menuitem":         "button",
+	"tab":              "button",
+	"option":           "button",
+	"treeitem":         "button",
+}
+
+// axTextRoles — блоки текста (как TEXT_TAGS у JS-сканера): выводятся без ID
+// в пределах TextBudget, если TextBudget > 0
+var axTextRoles = map[string]bool{
+	"heading":      true,
+	"paragraph":    true,
+	"listitem":     true,
+	"cell":         true,
+	"gridcell":     true,
+	"columnheader": true,
+	"rowheader":    true,
+	"blockquote":   true,
+	"caption":      true,
+	"Figcaption":   true,
+	"term":         true,
+	"definition":   true,
+}
+
+// scanAXTree строит элементы по дереву доступности основного документа.
+// Роль, имя и состояние берет Chrome, а не эвристики. Содержимое iframe
+// (у каждого фрейма свое дерево) этот наблюдатель не видит.
+func (s *BrowserService) scanAXTree(ctx context.Context) ([]scannedElement, string, error) {
+	axCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	page := s.CurrentPage.Context(axCtx)
+
+	// Объекты прошлого наблюдения больше не нужны — реестр в странице уже новый
+	_ = proto.RuntimeReleaseObjectGroup{ObjectGroup: axObjectGroup}.Call(page)
+
+	tree, err := proto.AccessibilityGetFullAXTree{}.Call(page)
+	if err != nil {
+		return nil, "", fmt.Errorf("ax tree: %w", err)
+	}
+
+	// Варианты внутри выпадающего списка — часть строки [CHOOSE], а не отдельные кнопки
+	roles := make(map[proto.AccessibilityAXNodeID]*proto.AccessibilityAXNode, len(tree.Nodes))
+	for _, node := range tree.Nodes {
+		roles[node.NodeID] = node
+	}
+	insideCombobox := func(node *proto.AccessibilityAXNode) bool {
+		for p := roles[node.ParentID]; p != nil; p = roles[p.ParentID] {
+			if p.Role != nil && p.Role.Value.String() == "combobox" {
+				return true
+			}
+		}
+		return false
+	}
+	// Текст внутри уже выданного блока или элемента — повтор (как insideText у JS-сканера)
+	taken := make(map[proto.AccessibilityAXNodeID]bool)
+	insideTaken := func(node *proto.AccessibilityAXNode) bool {
+		for p := roles[node.ParentID]; p != nil; p = roles[p.ParentID] {
+			if taken[p.NodeID] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var elements []scannedElement
+	var objects []interface{}
+	var interactive []bool
+	ids := 0
+	for _, node := range tree.Nodes {
+		if ids >= axMaxItems {
+			break
+		}
+		if node.Ignored || node.BackendDOMNodeID == 0 || node.Role == nil {
+			continue
+		}
+		role := node.Role.Value.String()
+		if axBool(node, proto.AccessibilityAXPropertyNameHidden) {
+			continue
+		}
+		tag, ok := axRoles[role]
+		if !ok {
+			if s.TextBudget <= 0 || !axTextRoles[role] || insideTaken(node) {
+				continue
+			}
+			// Текст и тег блока дает RegisterElementsScript — по правилам JS-сканера
+			resolved, err := proto.DOMResolveNode{BackendNodeID: node.BackendDOMNodeID, ObjectGroup: axObjectGroup}.Call(page)
+			if err != nil {
+				continue
+			}
+			taken[node.NodeID] = true
+			elements = append(elements, scannedElement{Role: role})
+			objects = append(objects, resolved.Object)
+			interactive = append(interactive, false)
+			continue
+		}
+		if (role == "option" || role == "menuitem") && insideCombobox(node) {
+			continue
+		}
+
+		resolved, err := proto.DOMResolveNode{
+			BackendNodeID: node.BackendDOMNodeID,
+			ObjectGroup:   axObjectGroup,
+		}.Call(page)
+		if err != nil {
+			continue // Узел успел исчезнуть из DOM
+		}
+
+		taken[node.NodeID] = true
+		ids++
+		name := axText(node.Name)
+		elements = append(elements, scannedElement{
+			ID:          ids,
+			Tag:         tag,
+			Text:        axSummaryText(tag, name, node),
+			Role:        role,
+			Name:        name,
+			Interactive: true,
+		})
+		objects = append(objects, resolved.Object)
+		interactive = append(interactive, true)
+	}
+
+	if len(elements) == 0 {
+		return nil, "", nil
+	}
+
+	// Тот же реестр, что у JS-сканера: GetElement, метки vision и стабильные ID
+	// работают без изменений
+	args := append([]interface{}{s.TextBudget, interactive}, objects...)
+	res, err := page.Eval(RegisterElementsScript, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("register ax elements: %w", err)
+	}
+	items := res.Value.Arr()
+	kept := elements[:0]
+	for i, el := range elements {
+		if i >= len(items) {
+			break
+		}
+		item := items[i]
+		el.Viewport = item.Get("viewport").String()
+		if !interactive[i] {
+			el.Tag, el.Text = item.Get("tag").String(), item.Get("text").String()
+			if el.Text == "" {
+				continue // Пустой блок, пункт из одной ссылки или бюджет кончился
+			}
+			kept = append(kept, el)
+			continue
+		}
+		el.Path = item.Get("path").String()
+		if control := item.Get("control"); !control.Nil() {
+			el.Tag = control.Get("tag").String()
+			el.Text = control.Get("text").String()
+		}
+		kept = append(kept, el)
+	}
+	return kept, "", nil
+}
+
+// axSummaryText — текст в формате JS-сканера: [INPUT] / [SELECT] / [NAVIGATE] / [ACTION]
+func axSummaryText(tag, name string, node *proto.AccessibilityAXNode) string {
+	switch tag {
+	case "input":
+		t := name
+		if t == "" {
+			t = axText(node.Value)
+		}
+		return "[INPUT] " + axOr(t, "Text Field")
+	case "checkbox":
+		state := " ( )"
+		if axText(axProperty(node, proto.AccessibilityAXPropertyNameChecked)) == "true" {
+			state = " (V)"
+		}
+		return "[SELECT] " + axOr(name, "Checkbox") + state
+	case "link":
+		return "[NAVIGATE] " + axOr(name, "Link")
+	default:
+		return "[ACTION] " + axOr(name, "Button")
+	}
+}
+
+func axProperty(node *proto.AccessibilityAXNode, name proto.AccessibilityAXPropertyName) *proto.AccessibilityAXValue {
+	for _, p := range node.Properties {
+		if p.Name == name {
+			return p.Value
+		}
+	}
+	return nil
+}
+
+func axBool(node *proto.AccessibilityAXNode, name proto.AccessibilityAXPropertyName) bool {
+	return axText(axProperty(node, name)) == "true"
+}
+
+// axText — значение AX-свойства строкой, схлопнутое и обрезанное как в JS-сканере
+func axText(v *proto.AccessibilityAXValue) string {
+	if v == nil || v.Value.Nil() {
+		return ""
+	}
+	t := strings.Join(strings.Fields(v.Value.String()), " ")
+	if r := []rune(t); len(r) > 50 {
+		t = string(r[:50])
+	}
+	return t
+}
+
+func axOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
@@ -0,0 +1,123 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// AX-наблюдатель на тех же фикстурах. На обычной форме оба наблюдателя должны
+// давать одинаковую сводку; на остальных расхождения только логируются — это
+// сравнение подходов (эвристики JS против ролей Chrome), а не ошибка.
+func TestObserve_AXBackend(t *testing.T) {
+	s := newTestService(t)
+	s.Observer = ObserverAX
+	base, _ := fixtureServer(t)
+
+	mustMatch := map[string]bool{"forms": true}
+	fixtures := []string{"forms", "contenteditable", "checkboxes", "links", "clickable", "frames", "form_controls"}
+
+	for _, name := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			ctx, state := openFixture(t, s, base+"/"+name+".html")
+
+			want, err := os.ReadFile(filepath.Join("testdata", name+".golden"))
+			if err != nil {
+				t.Fatalf("read golden: %v", err)
+			}
+
+			// Что бы AX ни нашел, каждый ID должен вести к элементу страницы
+			for _, m := range summaryID.FindAllStringSubmatch(state.DOMSummary, -1) {
+				id, _ := strconv.Atoi(m[1])
+				if _, err := s.GetElement(ctx, id); err != nil {
+					t.Errorf("GetElement(%d): %v", id, err)
+				}
+			}
+
+			// Если наблюдатели нашли разное число элементов, номера сдвигаются —
+			// сравниваем строки без них
+			ax, js := stripIDs(state.DOMSummary), stripIDs(string(want))
+			if ax == js {
+				return
+			}
+			if mustMatch[name] {
+				t.Errorf("AX and JS summaries differ for %s.html\n--- ax ---\n%s--- js ---\n%s", name, ax, js)
+			} else {
+				t.Logf("AX vs JS on %s.html\n--- ax ---\n%s--- js ---\n%s", name, ax, js)
+			}
+		})
+	}
+}
+
+// AX-наблюдатель выводит текст по тем же правилам и в том же TextBudget
+func TestObserve_AXTextBlocks(t *testing.T) {
+	s := newTestService(t)
+	s.Observer = ObserverAX
+	s.TextBudget = 3000
+
+	base, _ := fixtureServer(t)
+	ctx, state := openFixture(t, s, base+"/text.html")
+	for _, want := range []string{"    <h1> Заказ №42\n", "    <td> 1 200 ₽\n", "    <p> Вопросы? Напишите нам\n"} {
+		if !strings.Contains(state.DOMSummary, want) {
+			t.Errorf("Expected %q in AX summary:\n%s", want, state.DOMSummary)
+		}
+	}
+	if strings.Contains(state.DOMSummary, "<li> Помощь") {
+		t.Errorf("List item with a single link must not repeat its text:\n%s", state.DOMSummary)
+	}
+
+	s.TextBudget = 20
+	state, err := s.Observe(ctx)
+	if err != nil {
+		t.Fatalf("Observe failed: %v", err)
+	}
+	if !strings.Contains(state.DOMSummary, "    <p> Доставка за…\n") || strings.Contains(state.DOMSummary, "<td>") {
+		t.Errorf("Text budget not applied to AX summary:\n%s", state.DOMSummary)
+	}
+}
+
+// Под OBSERVER=ax поля форм выглядят так же, как у JS-сканера: select_option и
+// set_value ищут [CHOOSE] и [RANGE] при любом наблюдателе
+func TestForms_AXObserver(t *testing.T) {
+	s := newTestService(t)
+	s.Observer = ObserverAX
+
+	base, _ := fixtureServer(t)
+	ctx, state := openFixture(t, s, base+"/form_controls.html")
+
+	summary := stripIDs(state.DOMSummary)
+	for _, want := range []string{
+		"<select> [CHOOSE] Город: Казань {Москва | Казань | Санкт-Петербург | Самара}\n",
+		"<date> [DATE] Заезд = empty (YYYY-MM-DD)\n",
+		"<date> [DATE] Время = 09:00 (HH:MM)\n",
+		"<range> [RANGE] Громкость = 20 (0..50, step 5)\n",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("Expected %q in AX summary:\n%s", want, state.DOMSummary)
+		}
+	}
+	// Варианты списка не выдаются отдельными кнопками
+	if strings.Contains(summary, "[ACTION] Москва") {
+		t.Errorf("Options of <select> must not be separate elements:\n%s", state.DOMSummary)
+	}
+
+	ids := map[string]int{}
+	for _, m := range regexp.MustCompile(`(?m)^\[(\d+)\] <(\w+)>`).FindAllStringSubmatch(state.DOMSummary, -1) {
+		if _, ok := ids[m[2]]; !ok {
+			ids[m[2]], _ = strconv.Atoi(m[1])
+		}
+	}
+	if chosen, err := s.SelectOption(ctx, ids["select"], "санкт"); err != nil || chosen != "Санкт-Петербург" {
+		t.Errorf("SelectOption under AX: %q, %v", chosen, err)
+	}
+	if got, err := s.SetValue(ctx, ids["range"], "33"); err != nil || got != "35" {
+		t.Errorf("SetValue under AX: %q, %v", got, err)
+	}
+}
+
+func stripIDs(summary string) string {
+	return summaryID.ReplaceAllString(summary, "[#]")
+}
@@ -0,0 +1,85 @@
+package browser
+
+import (
+	"fmt"
+
+	"browser-agent/internal/entity"
+)
+
+// observedElement — элемент прошлого наблюдения для сравнения
+type observedElement struct {
+	tag  string
+	text string
+}
+
+// snapshot — элементы одного наблюдения в порядке документа
+type snapshot struct {
+	url      string
+	order    []int
+	elements map[int]observedElement
+	texts    []string // Блоки текста ("<p> ...") — у них нет ID, сравниваем по содержимому
+}
+
+func newSnapshot(url string, elements []scannedElement) *snapshot {
+	snap := &snapshot{
+		url:      url,
+		order:    make([]int, 0, len(elements)),
+		elements: make(map[int]observedElement, len(elements)),
+	}
+	for _, el := range elements {
+		if !el.Interactive {
+			snap.texts = append(snap.texts, fmt.Sprintf("<%s> %s", el.Tag, el.summaryText()))
+			continue
+		}
+		snap.order = append(snap.order, el.ID)
+		snap.elements[el.ID] = observedElement{tag: el.Tag, text: el.summaryText()}
+	}
+	return snap
+}
+
+// diffSnapshots сравнивает два наблюдения по стабильным ID
+func diffSnapshots(prev, cur *snapshot) *entity.DOMChanges {
+	changes := &entity.DOMChanges{}
+	if prev.url != cur.url {
+		changes.PrevURL = prev.url
+	}
+
+	for _, id := range cur.order {
+		el := cur.elements[id]
+		old, ok := prev.elements[id]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, fmt.Sprintf("[%d] <%s> %s", id, el.tag, el.text))
+		case old.text != el.text || old.tag != el.tag:
+			changes.Changed = append(changes.Changed, fmt.Sprintf("[%d] <%s> %s -> %s", id, el.tag, old.text, el.text))
+		}
+	}
+	for _, id := range prev.order {
+		if _, ok := cur.elements[id]; !ok {
+			el := prev.elements[id]
+			changes.Removed = append(changes.Removed, fmt.Sprintf("[%d] <%s> %s", id, el.tag, el.text))
+		}
+	}
+
+	// Новый текст — часто главный результат действия ("Сохранено", ошибка формы)
+	changes.Added = append(changes.Added, missingTexts(cur.texts, prev.texts)...)
+	changes.Removed = append(changes.Removed, missingTexts(prev.texts, cur.texts)...)
+	return changes
+}
+
+// missingTexts — блоки из texts, которых нет в other (с учетом повторов)
+func missingTexts(texts, other []string) []string {
+	left := make(map[string]int, len(other))
+	for _, t := range other {
+		left[t]++
+	}
+	var missing []string
+	for _, t := range texts {
+		if left[t] > 0 {
+			left[t]--
+			continue
+		}
+		missing = append(missing, t)
+	}
+	return missing
+}
@@ -0,0 +1,67 @@
+package browser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	prev := newSnapshot("https://mail.test/inbox", []scannedElement{
+		{ID: 1, Tag: "input", Text: "[INPUT] Поиск", Interactive: true},
+		{ID: 2, Tag: "checkbox", Text: "[SELECT] Спам ( )", Interactive: true},
+		{ID: 3, Tag: "button", Text: "[ACTION] Удалить", Interactive: true},
+	})
+	cur := newSnapshot("https://mail.test/inbox", []scannedElement{
+		{ID: 1, Tag: "input", Text: "[INPUT] Поиск", Interactive: true},
+		{ID: 2, Tag: "checkbox", Text: "[SELECT] Спам (V)", Interactive: true},
+		{ID: 4, Tag: "button", Text: "[ACTION] Вернуть", Interactive: true, Frame: "https://widget.test"},
+	})
+
+	changes := diffSnapshots(prev, cur)
+
+	if changes.PrevURL != "" {
+		t.Errorf("URL did not change, got PrevURL %q", changes.PrevURL)
+	}
+	if want := []string{"[4] <button> [ACTION] Вернуть [frame: https://widget.test]"}; !reflect.DeepEqual(changes.Added, want) {
+		t.Errorf("Added: got %v, want %v", changes.Added, want)
+	}
+	if want := []string{"[3] <button> [ACTION] Удалить"}; !reflect.DeepEqual(changes.Removed, want) {
+		t.Errorf("Removed: got %v, want %v", changes.Removed, want)
+	}
+	if want := []string{"[2] <checkbox> [SELECT] Спам ( ) -> [SELECT] Спам (V)"}; !reflect.DeepEqual(changes.Changed, want) {
+		t.Errorf("Changed: got %v, want %v", changes.Changed, want)
+	}
+
+	if same := diffSnapshots(cur, cur); !same.Empty() {
+		t.Errorf("Expected no changes, got %+v", same)
+	}
+	if moved := diffSnapshots(prev, newSnapshot("https://mail.test/spam", nil)); moved.PrevURL != "https://mail.test/inbox" || len(moved.Removed) != 3 {
+		t.Errorf("Unexpected diff after URL change: %+v", moved)
+	}
+}
+
+func TestDiffSnapshots_TextBlocks(t *testing.T) {
+	prev := newSnapshot("https://shop.test/cart", []scannedElement{
+		{Tag: "h1", Text: "Корзина"},
+		{ID: 1, Tag: "button", Text: "[ACTION] Оформить", Interactive: true},
+		{Tag: "td", Text: "1 200 ₽"},
+	})
+	cur := newSnapshot("https://shop.test/cart", []scannedElement{
+		{Tag: "h1", Text: "Корзина"},
+		{Tag: "p", Text: "Промокод применен"},
+		{ID: 1, Tag: "button", Text: "[ACTION] Оформить", Interactive: true},
+		{Tag: "td", Text: "1 080 ₽"},
+	})
+
+	changes := diffSnapshots(prev, cur)
+
+	if want := []string{"<p> Промокод применен", "<td> 1 080 ₽"}; !reflect.DeepEqual(changes.Added, want) {
+		t.Errorf("Added: got %v, want %v", changes.Added, want)
+	}
+	if want := []string{"<td> 1 200 ₽"}; !reflect.DeepEqual(changes.Removed, want) {
+		t.Errorf("Removed: got %v, want %v", changes.Removed, want)
+	}
+	if len(changes.Changed) != 0 {
+		t.Errorf("Text blocks have no IDs and cannot be changed, got %v", changes.Changed)
+	}
+}
@@ -0,0 +1,69 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"browser-agent/internal/entity"
+)
+
+const (
+	// defaultExtractRows — сколько строк отдавать, если max_rows не задан
+	defaultExtractRows = 50
+	// maxExtractRows — верхний предел max_rows: строки уходят в промпт
+	maxExtractRows = 200
+)
+
+// ExtractTable превращает HTML-таблицу в строки. id — любой элемент внутри
+// таблицы (или ее контейнер); 0 — самая длинная таблица страницы.
+func (s *BrowserService) ExtractTable(ctx context.Context, id, maxRows int) (*entity.ExtractedData, error) {
+	return s.extract(ctx, "table", ExtractTableScript, id, maxRows)
+}
+
+// ExtractList превращает повторяющиеся блоки (список, карточки, выдачу) в строки
+// text/link. id — элемент внутри одного из блоков; 0 — самая большая группа на странице.
+func (s *BrowserService) ExtractList(ctx context.Context, id, maxRows int) (*entity.ExtractedData, error) {
+	return s.extract(ctx, "list", ExtractListScript, id, maxRows)
+}
+
+func (s *BrowserService) extract(ctx context.Context, kind, script string, id, maxRows int) (*entity.ExtractedData, error) {
+	if maxRows <= 0 {
+		maxRows = defaultExtractRows
+	}
+	if maxRows > maxExtractRows {
+		maxRows = maxExtractRows
+	}
+
+	evalCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Без ID скрипт ищет по всей странице, с ID — вокруг элемента (в том числе во фрейме)
+	eval := s.CurrentPage.Context(evalCtx).Eval
+	if id > 0 {
+		el, err := s.GetElement(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("элемент ID %d не найден: %w", id, err)
+		}
+		eval = el.Context(evalCtx).Eval
+	}
+
+	res, err := eval(script, maxRows)
+	if err != nil {
+		return nil, fmt.Errorf("extract %s: %w", kind, err)
+	}
+
+	if res.Value.Nil() {
+		if id > 0 {
+			return nil, fmt.Errorf("no %s found around element %d", kind, id)
+		}
+		return nil, fmt.Errorf("no %s found on the page", kind)
+	}
+
+	data := &entity.ExtractedData{Kind: kind}
+	if err := json.Unmarshal([]byte(res.Value.String()), data); err != nil {
+		return nil, fmt.Errorf("json unmarshal: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,50 @@
+package browser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtract_TableAndList(t *testing.T) {
+	s := newTestService(t)
+	base, _ := fixtureServer(t)
+	ctx, state := openFixture(t, s, base+"/extract.html")
+	// [1] — кнопка "Купить" в первой строке таблицы, [5] — ссылка во второй карточке
+	for _, line := range []string{"[1] <button> [ACTION] Купить", "[5] <link> [NAVIGATE] Слон фарфоровый"} {
+		if !strings.Contains(state.DOMSummary, line+"\n") {
+			t.Fatalf("Expected %q in summary:\n%s", line, state.DOMSummary)
+		}
+	}
+
+	table, err := s.ExtractTable(ctx, 1, 2)
+	if err != nil {
+		t.Fatalf("ExtractTable failed: %v", err)
+	}
+	if !reflect.DeepEqual(table.Columns, []string{"Товар", "Цена", "col 3"}) {
+		t.Errorf("Columns: %v", table.Columns)
+	}
+	wantRows := []map[string]string{
+		{"Товар": "Чайник", "Цена": "1 200 ₽", "col 3": "Купить"},
+		{"Товар": "Тостер", "Цена": "2 500 ₽", "col 3": "Купить"},
+	}
+	if !reflect.DeepEqual(table.Rows, wantRows) || table.Total != 3 {
+		t.Errorf("Rows: %v (total %d)", table.Rows, table.Total)
+	}
+
+	// Без ID — единственная таблица страницы
+	if whole, err := s.ExtractTable(ctx, 0, 0); err != nil || len(whole.Rows) != 3 {
+		t.Errorf("ExtractTable without id: %+v, %v", whole, err)
+	}
+
+	list, err := s.ExtractList(ctx, 5, 0)
+	if err != nil {
+		t.Fatalf("ExtractList failed: %v", err)
+	}
+	if list.Total != 3 || list.Rows[1]["text"] != "Слон фарфоровый 250 монет" {
+		t.Errorf("Unexpected list: %+v", list)
+	}
+	if list.Rows[2]["link"] != base+"/p/3" {
+		t.Errorf("Link must be absolute, got %q", list.Rows[2]["link"])
+	}
+}
@@ -0,0 +1,159 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+// formResult — ответ SelectOptionScript и SetValueScript
+type formResult struct {
+	OK      bool     `json:"ok"`
+	Chosen  string   `json:"chosen"`
+	Actual  string   `json:"actual"`
+	Reason  string   `json:"reason"`
+	Options []string `json:"options"`
+}
+
+// SelectOption выбирает вариант <select> по видимому тексту и возвращает
+// текст выбранного варианта (при частичном совпадении он длиннее label)
+func (s *BrowserService) SelectOption(ctx context.Context, id int, label string) (string, error) {
+	res, err := s.evalForm(ctx, id, SelectOptionScript, label)
+	if err != nil {
+		return "", err
+	}
+	if !res.OK {
+		if len(res.Options) > 0 {
+			return "", fmt.Errorf("%s %q, options: %s", res.Reason, label, strings.Join(res.Options, " | "))
+		}
+		return "", fmt.Errorf("%s", res.Reason)
+	}
+	return res.Chosen, nil
+}
+
+// SetValue задает значение полю даты/времени, ползунку или полю цвета — туда,
+// куда type ввести не может. Возвращает значение, которое поле приняло.
+func (s *BrowserService) SetValue(ctx context.Context, id int, value string) (string, error) {
+	res, err := s.evalForm(ctx, id, SetValueScript, value)
+	if err != nil {
+		return "", err
+	}
+	if !res.OK {
+		return "", fmt.Errorf("%s: %q", res.Reason, value)
+	}
+	return res.Actual, nil
+}
+
+// UploadFile прикрепляет к <input type=file> файл из UploadDir. name — путь
+// относительно UploadDir; выйти за пределы каталога нельзя.
+func (s *BrowserService) UploadFile(ctx context.Context, id int, name string) error {
+	path, err := resolveUpload(s.UploadDir, name)
+	if err != nil {
+		return err
+	}
+
+	el, err := s.GetElement(ctx, id)
+	if err != nil {
+		return fmt.Errorf("элемент ID %d не найден: %w", id, err)
+	}
+
+	setCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	isFile, err := el.Context(setCtx).Eval(`() => this.tagName === 'INPUT' && this.type === 'file'`)
+	if err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+	if !isFile.Value.Bool() {
+		return fmt.Errorf("element %d is not a file input", id)
+	}
+
+	if err := el.Context(setCtx).SetFiles([]string{path}); err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+
+	s.ElementMap = make(map[int]*rod.Element)
+	return nil
+}
+
+// resolveUpload превращает имя файла в абсолютный путь внутри dir. Символические
+// ссылки раскрываются до проверки, чтобы ссылка из каталога наружу не проходила.
+func resolveUpload(dir, name string) (string, error) {
+	if dir == "" {
+		return "", errors.New("file uploads are disabled: UPLOAD_DIR is not set")
+	}
+	if name == "" {
+		return "", errors.New("file name is empty")
+	}
+
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("UPLOAD_DIR: %w", err)
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("UPLOAD_DIR: %w", err)
+	}
+
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, name)
+	}
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("file %q not found in UPLOAD_DIR", name)
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("file %q is outside UPLOAD_DIR", name)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%q is a directory", name)
+	}
+	return path, nil
+}
+
+func (s *BrowserService) evalForm(ctx context.Context, id int, script, arg string) (*formResult, error) {
+	el, err := s.GetElement(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("элемент ID %d не найден: %w", id, err)
+	}
+
+	highlightCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	_, _ = el.Context(highlightCtx).Eval(HighlightTypeScript)
+
+	evalCtx, evalCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer evalCancel()
+
+	val, err := el.Context(evalCtx).Eval(script, arg)
+	if err != nil {
+		return nil, fmt.Errorf("JS error: %w", err)
+	}
+
+	var res formResult
+	if err := json.Unmarshal([]byte(val.Value.String()), &res); err != nil {
+		return nil, fmt.Errorf("json unmarshal: %w", err)
+	}
+
+	// Форма могла перестроиться (зависимые поля, валидация)
+	s.ElementMap = make(map[int]*rod.Element)
+	return &res, nil
+}
@@ -0,0 +1,103 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveUpload(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "uploads")
+	if err := os.MkdirAll(filepath.Join(dir, "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{filepath.Join(dir, "cv.pdf"), filepath.Join(dir, "docs", "passport.jpg"), filepath.Join(base, "secret.txt")} {
+		if err := os.WriteFile(name, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Ссылка изнутри каталога наружу не должна открывать доступ
+	if err := os.Symlink(filepath.Join(base, "secret.txt"), filepath.Join(dir, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"cv.pdf", "docs/passport.jpg", filepath.Join(dir, "cv.pdf")} {
+		path, err := resolveUpload(dir, name)
+		if err != nil {
+			t.Errorf("resolveUpload(%q): %v", name, err)
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			t.Errorf("resolveUpload(%q) = %q, want an absolute path", name, path)
+		}
+	}
+
+	for _, name := range []string{"", "../secret.txt", filepath.Join(base, "secret.txt"), "link.txt", "missing.pdf", "docs"} {
+		if path, err := resolveUpload(dir, name); err == nil {
+			t.Errorf("resolveUpload(%q) = %q, want an error", name, path)
+		}
+	}
+
+	if _, err := resolveUpload("", "cv.pdf"); err == nil {
+		t.Error("Uploads must be disabled without UPLOAD_DIR")
+	}
+}
+
+// ID — по эталону form_controls.golden
+func TestForms_SelectSetValueUpload(t *testing.T) {
+	s := newTestService(t)
+	s.UploadDir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(s.UploadDir, "cv.pdf"), []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	base, _ := fixtureServer(t)
+	ctx, _ := openFixture(t, s, base+"/form_controls.html")
+
+	if chosen, err := s.SelectOption(ctx, 1, "санкт"); err != nil || chosen != "Санкт-Петербург" {
+		t.Errorf("SelectOption by part: %q, %v", chosen, err)
+	}
+	for _, label := range []string{"Самара", "а", "Тверь"} {
+		if _, err := s.SelectOption(ctx, 1, label); err == nil {
+			t.Errorf("SelectOption(%q) must fail (disabled, ambiguous, missing)", label)
+		}
+	}
+
+	if got, err := s.SetValue(ctx, 2, "2025-03-14"); err != nil || got != "2025-03-14" {
+		t.Errorf("SetValue date: %q, %v", got, err)
+	}
+	if _, err := s.SetValue(ctx, 2, "14.03.2025"); err == nil {
+		t.Error("SetValue must reject a date in the wrong format")
+	}
+	// Ползунок сам округляет до шага 5
+	if got, err := s.SetValue(ctx, 4, "33"); err != nil || got != "35" {
+		t.Errorf("SetValue range: %q, %v", got, err)
+	}
+	if _, err := s.SetValue(ctx, 6, "x"); err == nil {
+		t.Error("SetValue on a button must fail")
+	}
+
+	if err := s.UploadFile(ctx, 5, "cv.pdf"); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	if err := s.UploadFile(ctx, 6, "cv.pdf"); err == nil {
+		t.Error("UploadFile into a button must fail")
+	}
+
+	state, err := s.Observe(ctx)
+	if err != nil {
+		t.Fatalf("Observe failed: %v", err)
+	}
+	for _, line := range []string{
+		"[1] <select> [CHOOSE] Город: Санкт-Петербург {",
+		"[2] <date> [DATE] Заезд = 2025-03-14 (YYYY-MM-DD)\n",
+		"[4] <range> [RANGE] Громкость = 35 (0..50, step 5)\n",
+		"[5] <file> [FILE] Резюме (accept: .pdf) (attached: cv.pdf)\n",
+	} {
+		if !strings.Contains(state.DOMSummary, line) {
+			t.Errorf("Expected %q in summary:\n%s", line, state.DOMSummary)
+		}
+	}
+}
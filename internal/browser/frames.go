@@ -0,0 +1,100 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+// maxFrameDepth — глубина вложенности cross-origin фреймов, которую сканируем
+const maxFrameDepth = 3
+
+// frameScope — cross-origin фрейм, просканированный в собственном контексте
+type frameScope struct {
+	page   *rod.Page // Страница, привязанная к контексту фрейма (Element.Frame)
+	origin string
+}
+
+// scanCrossOriginFrames находит iframe, в которые не заходит ObserveElementsScript,
+// и запускает сканер внутри каждого с ID от nextID. textLeft — остаток бюджета
+// текста страницы, фреймы делят его по порядку. Ошибки отдельных фреймов
+// не ломают наблюдение: фрейм просто пропускается.
+func (s *BrowserService) scanCrossOriginFrames(ctx context.Context, parent *rod.Page, nextID, textLeft, depth int) []scannedElement {
+	if depth >= maxFrameDepth {
+		return nil
+	}
+
+	findCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	frames, err := parent.Context(findCtx).ElementsByJS(rod.Eval(CrossOriginFramesScript))
+	if err != nil || len(frames) == 0 {
+		return nil
+	}
+
+	var result []scannedElement
+	for _, frameEl := range frames {
+		elements, err := s.scanFrame(ctx, frameEl, nextID, textLeft, depth)
+		if err != nil {
+			if ctx.Err() != nil {
+				return result
+			}
+			fmt.Printf("⚠️ Не удалось просканировать фрейм: %v\n", err)
+			continue
+		}
+		result = append(result, elements...)
+		nextID += len(elements)
+		textLeft -= textLength(elements)
+	}
+	return result
+}
+
+// scanFrame сканирует один фрейм (и его собственные cross-origin фреймы)
+func (s *BrowserService) scanFrame(ctx context.Context, frameEl *rod.Element, startID, textLeft, depth int) ([]scannedElement, error) {
+	frame, err := frameEl.Frame()
+	if err != nil {
+		return nil, fmt.Errorf("frame: %w", err)
+	}
+
+	evalCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	origin, err := frame.Context(evalCtx).Eval(`() => location.origin`)
+	if err != nil {
+		return nil, fmt.Errorf("frame origin: %w", err)
+	}
+
+	res, err := frame.Context(evalCtx).Eval(ObserveElementsScript, startID, textLeft)
+	if err != nil {
+		return nil, fmt.Errorf("scan %s: %w", origin.Value.String(), err)
+	}
+
+	var elements []scannedElement
+	if err := json.Unmarshal([]byte(res.Value.String()), &elements); err != nil {
+		return nil, fmt.Errorf("json unmarshal: %w", err)
+	}
+
+	scope := &frameScope{page: frame, origin: origin.Value.String()}
+	s.frames = append(s.frames, scope)
+	for i := range elements {
+		elements[i].Frame = scope.origin
+		elements[i].scope = scope
+	}
+
+	nested := s.scanCrossOriginFrames(ctx, frame, startID+len(elements), textLeft-textLength(elements), depth+1)
+	elements = append(elements, nested...)
+
+	// Сканер внутри фрейма видит только его собственный вьюпорт. Если сам фрейм
+	// за пределами экрана родителя, туда же попадает и всё его содержимое.
+	posCtx, cancelPos := context.WithTimeout(ctx, time.Second)
+	defer cancelPos()
+	if pos, err := frameEl.Context(posCtx).Eval(ViewportPositionScript); err == nil && pos.Value.String() != viewportIn {
+		for i := range elements {
+			elements[i].Viewport = pos.Value.String()
+		}
+	}
+	return elements, nil
+}
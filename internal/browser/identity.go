@@ -0,0 +1,195 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+// forgetAfter — через сколько наблюдений без элемента реестр забывает его ID.
+// Navigate сбрасывает реестр целиком, а смена маршрута в SPA и бесконечная
+// лента — нет: без этого реестр рос бы всю сессию.
+const forgetAfter = 20
+
+// idRegistry выдает элементам стабильные ID по отпечатку (фрейм, тег, роль,
+// доступное имя, путь в DOM). Та же кнопка сохраняет ID между наблюдениями,
+// даже если выше нее что-то появилось или страница перерисовалась.
+type idRegistry struct {
+	next  int
+	byKey map[string]int   // Точный отпечаток → ID
+	keyOf map[int]string   // ID → его текущий точный отпечаток
+	loose map[string][]int // Отпечаток без пути → все ID, которые его имели
+
+	round    int            // Номер наблюдения
+	lastSeen map[int]int    // ID → в каком наблюдении элемент был последний раз
+	looseOf  map[int]string // ID → отпечаток без пути, под которым он лежит в loose
+}
+
+func newIDRegistry() *idRegistry {
+	return &idRegistry{
+		byKey:    make(map[string]int),
+		keyOf:    make(map[int]string),
+		loose:    make(map[string][]int),
+		lastSeen: make(map[int]int),
+		looseOf:  make(map[int]string),
+	}
+}
+
+func (el scannedElement) looseKey() string {
+	return el.Frame + "|" + el.Tag + "|" + el.Role + "|" + el.Name
+}
+
+func (el scannedElement) exactKey() string {
+	return el.looseKey() + "|" + el.Path
+}
+
+// assign возвращает стабильные ID для элементов одного наблюдения (в том же порядке).
+// 1. Точное совпадение отпечатка — элемент остался на месте.
+// 2. Совпадение без пути, если кандидат ровно один — элемент переехал в DOM.
+// 3. Иначе — новый ID.
+// Блоки текста (Interactive == false) ID не получают — у них остается 0.
+func (r *idRegistry) assign(elements []scannedElement) []int {
+	ids := make([]int, len(elements))
+	keys := make([]string, len(elements))
+	used := make(map[int]bool)
+
+	// Одинаковые отпечатки в одном наблюдении различаем порядковым номером
+	occurrences := make(map[string]int)
+	for i, el := range elements {
+		if !el.Interactive {
+			continue
+		}
+		key := el.exactKey()
+		occurrences[key]++
+		if n := occurrences[key]; n > 1 {
+			key = fmt.Sprintf("%s#%d", key, n)
+		}
+		keys[i] = key
+
+		if id, ok := r.byKey[key]; ok && !used[id] {
+			ids[i] = id
+			used[id] = true
+		}
+	}
+
+	for i, el := range elements {
+		// Без имени элементы неразличимы (пустые поля, иконки) — только точное совпадение
+		if ids[i] != 0 || el.Name == "" || !el.Interactive {
+			continue
+		}
+		var free []int
+		for _, id := range r.loose[el.looseKey()] {
+			if !used[id] {
+				free = append(free, id)
+			}
+		}
+		if len(free) == 1 {
+			ids[i] = free[0]
+			used[free[0]] = true
+		}
+	}
+
+	for i, el := range elements {
+		if !el.Interactive {
+			continue
+		}
+		if ids[i] == 0 {
+			r.next++
+			ids[i] = r.next
+			r.loose[el.looseKey()] = append(r.loose[el.looseKey()], ids[i])
+			r.looseOf[ids[i]] = el.looseKey()
+		}
+		if old, ok := r.keyOf[ids[i]]; ok && old != keys[i] {
+			delete(r.byKey, old)
+		}
+		r.byKey[keys[i]] = ids[i]
+		r.keyOf[ids[i]] = keys[i]
+	}
+
+	r.round++
+	for i, el := range elements {
+		if el.Interactive {
+			r.lastSeen[ids[i]] = r.round
+		}
+	}
+	r.forget()
+	return ids
+}
+
+// forget убирает ID, которых не было forgetAfter наблюдений подряд. Вернувшийся
+// после этого элемент получит новый ID — модель его все равно давно не видела.
+func (r *idRegistry) forget() {
+	for id, seen := range r.lastSeen {
+		if r.round-seen < forgetAfter {
+			continue
+		}
+		key := r.keyOf[id]
+		if r.byKey[key] == id {
+			delete(r.byKey, key)
+		}
+		delete(r.keyOf, id)
+		delete(r.lastSeen, id)
+
+		loose := r.looseOf[id]
+		delete(r.looseOf, id)
+		ids := r.loose[loose]
+		for i, other := range ids {
+			if other == id {
+				ids = append(ids[:i], ids[i+1:]...)
+				break
+			}
+		}
+		if len(ids) == 0 {
+			delete(r.loose, loose)
+		} else {
+			r.loose[loose] = ids
+		}
+	}
+}
+
+// applyIDs переписывает временные ID сканера на стабильные в реестре каждой
+// страницы (основной и фреймов) и запоминает, в каком фрейме живет каждый ID
+func (s *BrowserService) applyIDs(ctx context.Context, elements []scannedElement, ids []int) error {
+	pairs := make(map[*frameScope][][2]int)
+	for i, el := range elements {
+		if !el.Interactive {
+			continue
+		}
+		pairs[el.scope] = append(pairs[el.scope], [2]int{el.ID, ids[i]})
+	}
+
+	evalCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	s.owners = make(map[int]*frameScope)
+	for scope, p := range pairs {
+		page := s.CurrentPage
+		if scope != nil {
+			page = scope.page
+		}
+		if _, err := page.Context(evalCtx).Eval(RemapIDsScript, p); err != nil {
+			return fmt.Errorf("remap ids: %w", err)
+		}
+		for _, pair := range p {
+			if scope != nil {
+				s.owners[pair[1]] = scope
+			}
+		}
+	}
+
+	for i := range elements {
+		elements[i].ID = ids[i]
+	}
+	return nil
+}
+
+// pageFor — страница (основная или фрейм), в реестре которой лежит элемент с этим ID,
+// и origin фрейма (пусто для основной страницы)
+func (s *BrowserService) pageFor(id int) (*rod.Page, string) {
+	if f, ok := s.owners[id]; ok {
+		return f.page, f.origin
+	}
+	return s.CurrentPage, ""
+}
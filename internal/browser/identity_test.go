@@ -0,0 +1,167 @@
+package browser
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func element(tag, name, path string) scannedElement {
+	return scannedElement{Tag: tag, Name: name, Path: path, Interactive: true}
+}
+
+func TestIDRegistry_StableAcrossObservations(t *testing.T) {
+	r := newIDRegistry()
+
+	first := r.assign([]scannedElement{
+		element("button", "Войти", "html:1/body:1/button:1"),
+		element("input", "Email", "html:1/body:1/input:1"),
+	})
+	if !reflect.DeepEqual(first, []int{1, 2}) {
+		t.Fatalf("First observation: got %v", first)
+	}
+
+	// Сверху появился баннер: у кнопки сменился путь, у поля — нет
+	second := r.assign([]scannedElement{
+		element("link", "Акция", "html:1/body:1/a:1"),
+		element("button", "Войти", "html:1/body:1/div:1/button:1"),
+		element("input", "Email", "html:1/body:1/input:1"),
+	})
+	if !reflect.DeepEqual(second, []int{3, 1, 2}) {
+		t.Errorf("Second observation: got %v, want [3 1 2]", second)
+	}
+
+	// Баннер исчез — все ID прежние
+	third := r.assign([]scannedElement{
+		element("button", "Войти", "html:1/body:1/div:1/button:1"),
+		element("input", "Email", "html:1/body:1/input:1"),
+	})
+	if !reflect.DeepEqual(third, []int{1, 2}) {
+		t.Errorf("Third observation: got %v, want [1 2]", third)
+	}
+}
+
+func TestIDRegistry_AmbiguousElements(t *testing.T) {
+	r := newIDRegistry()
+
+	// Три одинаковые кнопки "Удалить" различаются только путем
+	r.assign([]scannedElement{
+		element("button", "Удалить", "html:1/body:1/li:1/button:1"),
+		element("button", "Удалить", "html:1/body:1/li:2/button:1"),
+		element("button", "Удалить", "html:1/body:1/li:3/button:1"),
+	})
+
+	// Первую строку удалили, остальные сдвинулись: li:2 -> li:1, li:3 -> li:2.
+	// По точному пути совпадают чужие ID — это допустимо, главное без дублей.
+	ids := r.assign([]scannedElement{
+		element("button", "Удалить", "html:1/body:1/li:1/button:1"),
+		element("button", "Удалить", "html:1/body:1/li:2/button:1"),
+	})
+	if ids[0] == ids[1] {
+		t.Errorf("Duplicate IDs: %v", ids)
+	}
+
+	// Безымянные поля не склеиваются по имени: новое поле получает новый ID
+	r2 := newIDRegistry()
+	r2.assign([]scannedElement{element("input", "", "html:1/body:1/input:1")})
+	ids = r2.assign([]scannedElement{element("input", "", "html:1/body:1/form:1/input:1")})
+	if !reflect.DeepEqual(ids, []int{2}) {
+		t.Errorf("Unnamed element must get a new ID, got %v", ids)
+	}
+}
+
+func TestIDRegistry_SameKeyTwiceInOneObservation(t *testing.T) {
+	r := newIDRegistry()
+
+	ids := r.assign([]scannedElement{
+		element("clickable", "Item", "html:1/body:1/div:1"),
+		element("clickable", "Item", "html:1/body:1/div:1"),
+	})
+	if !reflect.DeepEqual(ids, []int{1, 2}) {
+		t.Fatalf("Got %v", ids)
+	}
+	if again := r.assign([]scannedElement{
+		element("clickable", "Item", "html:1/body:1/div:1"),
+		element("clickable", "Item", "html:1/body:1/div:1"),
+	}); !reflect.DeepEqual(again, ids) {
+		t.Errorf("Got %v, want %v", again, ids)
+	}
+}
+
+func TestIDRegistry_TextBlocksHaveNoID(t *testing.T) {
+	r := newIDRegistry()
+
+	ids := r.assign([]scannedElement{
+		{Tag: "h1", Text: "Заказ оформлен"},
+		element("button", "Назад", "html:1/body:1/button:1"),
+		{Tag: "p", Text: "Номер заказа 42"},
+	})
+	if !reflect.DeepEqual(ids, []int{0, 1, 0}) {
+		t.Errorf("Got %v, want [0 1 0]", ids)
+	}
+}
+
+func TestIDRegistry_ForgetsLongGoneElements(t *testing.T) {
+	r := newIDRegistry()
+
+	// Лента SPA: каждое наблюдение — новые карточки, реестр не должен расти без конца
+	for i := 0; i < 100; i++ {
+		r.assign([]scannedElement{
+			element("button", "Меню", "html:1/body:1/nav:1/button:1"),
+			element("link", fmt.Sprintf("Пост %d", i), fmt.Sprintf("html:1/body:1/div:%d/a:1", i+1)),
+		})
+	}
+	if len(r.keyOf) > forgetAfter+1 || len(r.byKey) > forgetAfter+1 || len(r.loose) > forgetAfter+1 {
+		t.Errorf("Registry keeps %d ids, %d keys, %d loose keys", len(r.keyOf), len(r.byKey), len(r.loose))
+	}
+
+	// Элемент, который есть в каждом наблюдении, сохраняет ID
+	ids := r.assign([]scannedElement{element("button", "Меню", "html:1/body:1/nav:1/button:1")})
+	if ids[0] != 1 {
+		t.Errorf("Persistent element lost its ID: got %d, want 1", ids[0])
+	}
+
+	// Ненадолго пропавший элемент возвращается со своим ID, давно пропавший — с новым
+	recent := r.assign([]scannedElement{element("link", "Пост 95", "html:1/body:1/div:96/a:1")})
+	old := r.assign([]scannedElement{element("link", "Пост 3", "html:1/body:1/div:4/a:1")})
+	if recent[0] != 97 {
+		t.Errorf("Recent element: got ID %d, want 97", recent[0])
+	}
+	if old[0] <= 101 {
+		t.Errorf("Forgotten element must get a new ID, got %d", old[0])
+	}
+}
+
+func TestObserve_StableIDs(t *testing.T) {
+	s := newTestService(t)
+	base, _ := fixtureServer(t)
+	ctx, _ := openFixture(t, s, base+"/forms.html")
+
+	// Перерисовка: сверху появилась новая кнопка, всё остальное сдвинулось
+	s.CurrentPage.MustEval(`() => document.body.insertAdjacentHTML('afterbegin', '<button>Новая</button>')`)
+
+	state, err := s.Observe(ctx)
+	if err != nil {
+		t.Fatalf("Observe failed: %v", err)
+	}
+	for _, line := range []string{"[1] <input> [INPUT] Email", "[10] <button> [ACTION] Сбросить", "[11] <button> [ACTION] Новая"} {
+		if !strings.Contains(state.DOMSummary, line+"\n") {
+			t.Errorf("Expected %q in summary:\n%s", line, state.DOMSummary)
+		}
+	}
+
+	// Остальные элементы те же — в разнице только новая кнопка
+	if state.Changes == nil || !reflect.DeepEqual(state.Changes.Added, []string{"[11] <button> [ACTION] Новая"}) ||
+		len(state.Changes.Removed) != 0 || len(state.Changes.Changed) != 0 {
+		t.Errorf("Unexpected changes: %+v", state.Changes)
+	}
+
+	// ID по-прежнему ведет к тому же элементу
+	if err := s.Type(ctx, 1, "a@b.c"); err != nil {
+		t.Fatalf("Type failed: %v", err)
+	}
+	if got := s.CurrentPage.MustEval(`() => document.querySelector('input[placeholder=Email]').value`).String(); got != "a@b.c" {
+		t.Errorf("Typed into wrong element, Email value %q", got)
+	}
+}
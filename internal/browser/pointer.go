@@ -0,0 +1,186 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// DoubleClick — двойной клик (открыть файл, редактировать ячейку, выделить слово)
+func (s *BrowserService) DoubleClick(ctx context.Context, id int) error {
+	return s.mouseClick(ctx, id, proto.InputMouseButtonLeft, 2)
+}
+
+// RightClick — клик правой кнопкой: открывает контекстное меню страницы
+func (s *BrowserService) RightClick(ctx context.Context, id int) error {
+	return s.mouseClick(ctx, id, proto.InputMouseButtonRight, 1)
+}
+
+// Hover наводит курсор на элемент: раскрывает меню и подсказки, которые
+// появляются только под мышью. Вкладок наведение не открывает — их не ждем.
+func (s *BrowserService) Hover(ctx context.Context, id int) error {
+	el, err := s.GetElement(ctx, id)
+	if err != nil {
+		return fmt.Errorf("элемент ID %d не найден: %w", id, err)
+	}
+	s.highlight(ctx, el, HighlightHoverScript)
+
+	hoverCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := el.Context(hoverCtx).Hover(); err != nil {
+		fmt.Printf("⚠️ Наведение мышью не удалось (%v), пробую JS...\n", err)
+		if jsErr := s.dispatchJS(ctx, el, "hover"); jsErr != nil {
+			return fmt.Errorf("наведение провалилось: %w", jsErr)
+		}
+	}
+
+	s.ElementMap = make(map[int]*rod.Element)
+	return nil
+}
+
+// Drag перетаскивает элемент fromID на элемент toID. HTML5 drag-and-drop
+// (draggable="true") синтетическими событиями мыши не запускается — для него
+// события drag* отправляются из JS; остальное (сортировки, ползунки на
+// mousedown/mousemove) тащится мышью с промежуточными шагами.
+func (s *BrowserService) Drag(ctx context.Context, fromID, toID int) error {
+	from, err := s.GetElement(ctx, fromID)
+	if err != nil {
+		return fmt.Errorf("элемент ID %d не найден: %w", fromID, err)
+	}
+	to, err := s.GetElement(ctx, toID)
+	if err != nil {
+		return fmt.Errorf("элемент ID %d не найден: %w", toID, err)
+	}
+	s.highlight(ctx, from, HighlightClickScript)
+	s.highlight(ctx, to, HighlightClickScript)
+
+	return s.watchTabs(ctx, func() error {
+		dragCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+
+		native, err := from.Context(dragCtx).Eval(`() => this.draggable === true`)
+		if err != nil {
+			return fmt.Errorf("drag: %w", err)
+		}
+		if native.Value.Bool() {
+			if _, err := from.Context(dragCtx).Eval(DragAndDropScript, to.Object); err != nil {
+				return fmt.Errorf("drag: %w", err)
+			}
+			return nil
+		}
+		return dragMouse(dragCtx, from, to)
+	})
+}
+
+// dragMouse — нажать на from, провести курсор до to в несколько шагов и отпустить.
+// Точку цели берем без проверки перекрытия: ее как раз закрывает перетаскиваемый элемент.
+func dragMouse(ctx context.Context, from, to *rod.Element) error {
+	start, err := from.Context(ctx).WaitInteractable()
+	if err != nil {
+		return fmt.Errorf("drag source: %w", err)
+	}
+	shape, err := to.Context(ctx).Shape()
+	if err != nil {
+		return fmt.Errorf("drag target: %w", err)
+	}
+	end := shape.OnePointInside()
+	if end == nil {
+		return fmt.Errorf("drag target is not visible")
+	}
+
+	mouse := from.Page().Context(ctx).Mouse
+	if err := mouse.MoveTo(*start); err != nil {
+		return err
+	}
+	if err := mouse.Down(proto.InputMouseButtonLeft, 1); err != nil {
+		return err
+	}
+	// Многие библиотеки начинают перетаскивание только после сдвига на несколько пикселей
+	if err := mouse.MoveLinear(*end, 10); err != nil {
+		_ = mouse.Up(proto.InputMouseButtonLeft, 1)
+		return err
+	}
+	return mouse.Up(proto.InputMouseButtonLeft, 1)
+}
+
+// mouseClick — клик нужной кнопкой с JS-запасным вариантом, переходом в новую
+// вкладку, если она открылась, и ожиданием загрузки
+func (s *BrowserService) mouseClick(ctx context.Context, id int, button proto.InputMouseButton, count int) error {
+	el, err := s.GetElement(ctx, id)
+	if err != nil {
+		return fmt.Errorf("элемент ID %d не найден: %w", id, err)
+	}
+	s.highlight(ctx, el, HighlightClickScript)
+
+	event := "click"
+	if button == proto.InputMouseButtonRight {
+		event = "contextmenu"
+	} else if count == 2 {
+		event = "dblclick"
+	}
+
+	return s.watchTabs(ctx, func() error {
+		clickCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		if err := el.Context(clickCtx).Click(button, count); err != nil {
+			fmt.Printf("⚠️ Обычный %s не удался (%v), пробую JS...\n", event, err)
+			if jsErr := s.dispatchJS(ctx, el, event); jsErr != nil {
+				return fmt.Errorf("все методы клика провалились: %w", jsErr)
+			}
+		}
+		return nil
+	})
+}
+
+// watchTabs выполняет действие и переключается на вкладку, если оно ее открыло,
+// или ждет загрузки, если оно начало переход. Подписка на события — до действия,
+// так что ждать приходится только actionGrace, а не фиксированную паузу.
+// Кэш элементов после действия сбрасывается.
+func (s *BrowserService) watchTabs(ctx context.Context, action func() error) error {
+	s.trackRequests()
+	watch := s.watchNavigation(ctx)
+	defer watch.stop()
+
+	if err := action(); err != nil {
+		return err
+	}
+
+	select {
+	case targetID := <-watch.tabs:
+		if newPage, err := s.browser.PageFromTarget(targetID); err == nil {
+			fmt.Printf("🔀 Новая вкладка: %s\n", safeGetURL(newPage))
+			s.activatePage(ctx, newPage)
+		}
+	case <-watch.started:
+		s.safeWaitLoad(ctx, 5*time.Second)
+	case <-time.After(actionGrace):
+	case <-ctx.Done():
+	}
+
+	// ⚡ ВАЖНО: Очищаем кэш после действия (DOM изменился!)
+	s.ElementMap = make(map[int]*rod.Element)
+
+	return nil
+}
+
+// highlight — подсветка элемента перед действием (с таймаутом, ошибки не важны)
+func (s *BrowserService) highlight(ctx context.Context, el *rod.Element, script string) {
+	highlightCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	_, _ = el.Context(highlightCtx).Eval(script)
+}
+
+// dispatchJS — запасной вариант, когда мышь не дотягивается (элемент перекрыт
+// или вне экрана): событие отправляется прямо элементу
+func (s *BrowserService) dispatchJS(ctx context.Context, el *rod.Element, event string) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := el.Context(ctx).Eval(DispatchMouseEventScript, event)
+	return err
+}
@@ -0,0 +1,86 @@
+package browser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPointer_HoverDoubleRightClickDrag(t *testing.T) {
+	s := newTestService(t)
+	base, _ := fixtureServer(t)
+	ctx, state := openFixture(t, s, base+"/pointer.html")
+	want := "[1] <clickable> [CLICK] Каталог\n[2] <button> [ACTION] Дважды\n[3] <button> [ACTION] Правой\n" +
+		"[4] <draggable> [DRAG] Задача\n[5] <button> [ACTION] Готово\n[6] <clickable> [CLICK] Ползунок\n[7] <button> [ACTION] Отметка\n"
+	if state.DOMSummary != want {
+		t.Fatalf("Unexpected summary:\n%s", state.DOMSummary)
+	}
+
+	text := func(id string) string {
+		res, err := s.CurrentPage.Eval(`(id) => { const el = document.getElementById(id); return id === 'card' ? el.parentElement.id : el.textContent; }`, id)
+		if err != nil {
+			t.Fatalf("Eval: %v", err)
+		}
+		return res.Value.String()
+	}
+
+	// Пункт меню появляется только под курсором
+	if err := s.Hover(ctx, 1); err != nil {
+		t.Fatalf("Hover failed: %v", err)
+	}
+	state, err := s.Observe(ctx)
+	if err != nil {
+		t.Fatalf("Observe failed: %v", err)
+	}
+	if !strings.Contains(state.DOMSummary, "<link> [NAVIGATE] Телевизоры\n") {
+		t.Errorf("Hover did not reveal the submenu:\n%s", state.DOMSummary)
+	}
+
+	if err := s.DoubleClick(ctx, 2); err != nil || text("dbl") != "Открыто" {
+		t.Errorf("DoubleClick: %v, button text %q", err, text("dbl"))
+	}
+	if err := s.RightClick(ctx, 3); err != nil || text("ctx") != "Меню" {
+		t.Errorf("RightClick: %v, button text %q", err, text("ctx"))
+	}
+
+	// HTML5 drag-and-drop: карточка переезжает в колонку "Готово"
+	if err := s.Drag(ctx, 4, 5); err != nil || text("card") != "done" {
+		t.Errorf("Drag (html5): %v, card is in %q", err, text("card"))
+	}
+	// Перетаскивание мышью: отпущено над отметкой, по пути были промежуточные движения
+	if err := s.Drag(ctx, 6, 7); err != nil {
+		t.Fatalf("Drag (mouse) failed: %v", err)
+	}
+	status := strings.Fields(text("status"))
+	if len(status) != 2 || status[0] != "mark" || status[1] == "0" || status[1] == "1" {
+		t.Errorf("Drag (mouse): status %q", text("status"))
+	}
+}
+
+// Подсветка выполняется на самом элементе (this), аргументов у нее нет
+func TestHighlightScripts(t *testing.T) {
+	s := newTestService(t)
+	base, _ := fixtureServer(t)
+	ctx, _ := openFixture(t, s, base+"/checkboxes.html")
+
+	el, err := s.CurrentPage.Context(ctx).Element("#native")
+	if err != nil {
+		t.Fatalf("Element: %v", err)
+	}
+
+	for name, script := range map[string]string{
+		"click": HighlightClickScript,
+		"type":  HighlightTypeScript,
+		"hover": HighlightHoverScript,
+	} {
+		if _, err := el.Eval(script); err != nil {
+			t.Errorf("%s highlight failed: %v", name, err)
+		}
+	}
+	res, err := el.Eval(`() => this.style.border + '|' + this.style.outline`)
+	if err != nil {
+		t.Fatalf("Eval: %v", err)
+	}
+	if got := res.Value.Str(); !strings.Contains(got, "blue") || !strings.Contains(got, "dotted") {
+		t.Errorf("Highlight styles not applied: %q", got)
+	}
+}
@@ -7,27 +7,64 @@ import (
 	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/proto"
 )
 
-func (s *BrowserService) Observe() (*entity.BrowserState, error) {
+// scannedElement — элемент из ObserveElementsScript
+type scannedElement struct {
+	ID          int    `json:"id"`
+	Tag         string `json:"tag"`
+	Text        string `json:"text"`
+	Role        string `json:"role"`
+	Interactive bool   `json:"interactive"` // false — блок текста страницы, без ID
+	Viewport    string `json:"viewport"`    // in / above / below — где элемент относительно экрана
+
+	// Отпечаток для стабильного ID (см. idRegistry)
+	Name string `json:"name"` // Доступное имя без значения поля и состояния
+	Path string `json:"path"` // tag:n/tag:n/... с отметками #shadow и #frame
+
+	Frame string      `json:"-"` // Origin cross-origin фрейма; пусто — элемент основной страницы
+	scope *frameScope // Фрейм, в реестре которого лежит элемент (nil — основная страница)
+}
+
+// summaryText — текст элемента в DOMSummary (с origin, если он из cross-origin фрейма)
+func (el scannedElement) summaryText() string {
+	if el.Frame != "" {
+		return el.Text + " [frame: " + el.Frame + "]"
+	}
+	return el.Text
+}
+
+// textLength — сколько символов бюджета текста заняли блоки текста
+func textLength(elements []scannedElement) int {
+	n := 0
+	for _, el := range elements {
+		if !el.Interactive {
+			n += utf8.RuneCountInString(el.Text)
+		}
+	}
+	return n
+}
+
+func (s *BrowserService) Observe(ctx context.Context) (*entity.BrowserState, error) {
 	// 1. Проверка живости вкладки (без изменений)
 	if s.CurrentPage != nil {
-		if _, err := s.CurrentPage.Info(); err != nil {
+		if _, err := s.CurrentPage.Context(ctx).Info(); err != nil {
 			fmt.Println("⚠️ Текущая вкладка мертва. Ищу живые...")
 			s.CurrentPage = nil
 		}
 	}
 	if s.CurrentPage == nil {
-		pages, err := s.browser.Pages()
+		pages, err := s.browser.Context(ctx).Pages()
 		if err == nil && len(pages) > 0 {
 			fmt.Println("🔄 Переключился на другую открытую вкладку.")
 			s.CurrentPage = pages[0]
 		} else {
 			fmt.Println("🆕 Все вкладки закрыты. Создаю новую...")
-			page, err := s.browser.Page(proto.TargetCreateTarget{URL: "google.com"})
+			page, err := s.browser.Context(ctx).Page(proto.TargetCreateTarget{URL: "google.com"})
 			if err != nil {
 				return nil, fmt.Errorf("не удалось воскресить браузер: %w", err)
 			}
@@ -35,93 +72,157 @@ func (s *BrowserService) Observe() (*entity.BrowserState, error) {
 		}
 	}
 
-	// 2. Очищаем карту
+	// 2. Очищаем карту и фреймы прошлого наблюдения
 	s.ElementMap = make(map[int]*rod.Element)
+	s.frames = nil
+	s.owners = nil
 
-	info, err := s.CurrentPage.Info()
+	info, err := s.CurrentPage.Context(ctx).Info()
 	if err != nil {
 		return nil, err
 	}
 
-	// 3. ⚡ БЫСТРОЕ ожидание — только 1-2 секунды
-	tryWaitStable(s.CurrentPage, 2*time.Second)
-
-	// 4. Выполняем JS с таймаутом
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	// 3. ⚡ Ждем загрузки и тишины DOM, но не дольше 2 секунд: не дождались —
+	// сканируем как есть
+	_ = s.Wait(ctx, observeWait)
 
-	res, err := s.CurrentPage.Context(ctx).Eval(ObserveElementsScript)
+	// 4. Собираем элементы: JS-сканер (по умолчанию) или дерево доступности
+	scan := s.scanJS
+	if s.Observer == ObserverAX {
+		scan = s.scanAXTree
+	}
+	elements, placeholder, err := scan(ctx)
 	if err != nil {
-		fmt.Printf("⚠️ Ошибка JS-парсинга: %v\n", err)
-		return &entity.BrowserState{
-			URL:        info.URL,
-			Title:      info.Title,
-			DOMSummary: "⚠️ Page is loading... (JS timed out)",
-		}, nil
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
 	}
-
-	jsonString := res.Value.String()
-	if jsonString == "" || jsonString == "null" {
+	if placeholder != "" {
 		return &entity.BrowserState{
 			URL:        info.URL,
 			Title:      info.Title,
-			DOMSummary: "Page is empty",
+			DOMSummary: placeholder,
 		}, nil
 	}
 
-	var elements []struct {
-		ID          int    `json:"id"`
-		Tag         string `json:"tag"`
-		Text        string `json:"text"`
-		Role        string `json:"role"`
-		Interactive bool   `json:"interactive"`
-	}
+	// 5. Лимит: при переполнении в сводке остается то, что на экране и рядом
+	elements, off := limitElements(elements, maxElements)
 
-	if err := json.Unmarshal([]byte(jsonString), &elements); err != nil {
-		return nil, fmt.Errorf("json unmarshal: %w", err)
+	// 6. Временные ID сканера → стабильные ID по отпечатку элемента
+	if s.ids == nil {
+		s.ids = newIDRegistry()
+	}
+	if err := s.applyIDs(ctx, elements, s.ids.assign(elements)); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
 	}
 
-	// 5. ⚡ СТРОИМ SUMMARY БЕЗ ЗАПРОСОВ К БРАУЗЕРУ
+	// 7. ⚡ СТРОИМ SUMMARY БЕЗ ЗАПРОСОВ К БРАУЗЕРУ
 	var sb strings.Builder
+	sb.WriteString(offscreenLine(off.above, off.hiddenAbove, "above", "up"))
 
 	for _, el := range elements {
 		// ❌ УБРАЛИ: s.CurrentPage.Element() — это было медленно!
 		// Элементы найдём ЛЕНИВО при клике/вводе
 
+		// Пометка [above]/[below] только в сводке: в разнице наблюдений
+		// прокрутка не должна выглядеть как изменение каждого элемента
+		text := el.summaryText() + viewportSuffix(el)
 		if el.Interactive {
-			sb.WriteString(fmt.Sprintf("[%d] <%s> %s\n", el.ID, el.Tag, el.Text))
+			sb.WriteString(fmt.Sprintf("[%d] <%s> %s\n", el.ID, el.Tag, text))
 		} else {
-			sb.WriteString(fmt.Sprintf("    <%s> %s\n", el.Tag, el.Text))
+			sb.WriteString(fmt.Sprintf("    <%s> %s\n", el.Tag, text))
 		}
 	}
 
-	if len(elements) >= 300 {
-		sb.WriteString("\n... (truncated) ...\n")
-	}
+	sb.WriteString(offscreenLine(off.below, off.hiddenBelow, "below", "down"))
 
 	domSummary := sb.String()
 	if domSummary == "" {
 		domSummary = "No elements found"
 	}
 
-	return &entity.BrowserState{
+	state := &entity.BrowserState{
 		URL:        info.URL,
 		Title:      info.Title,
 		DOMSummary: domSummary,
-	}, nil
+	}
+
+	// 8. Разница с прошлым наблюдением: агент видит, сработало ли действие
+	snap := newSnapshot(info.URL, elements)
+	if s.lastSnapshot != nil {
+		state.Changes = diffSnapshots(s.lastSnapshot, snap)
+	}
+	s.lastSnapshot = snap
+
+	// 9. 👁 Скриншот с метками — только в режиме Vision. Без картинки агент
+	// продолжает работать по тексту, поэтому ошибка не фатальна.
+	if s.Vision {
+		shot, err := s.MarkedScreenshot(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			fmt.Printf("⚠️ Не удалось снять скриншот: %v\n", err)
+		} else {
+			state.Screenshot = shot
+		}
+	}
+
+	return state, nil
+}
+
+// scanJS — наблюдатель по умолчанию: ObserveElementsScript в странице и в каждом
+// cross-origin фрейме. placeholder — готовый DOMSummary, если страница еще
+// грузится или пуста.
+func (s *BrowserService) scanJS(ctx context.Context) ([]scannedElement, string, error) {
+	evalCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := s.CurrentPage.Context(evalCtx).Eval(ObserveElementsScript, 1, s.TextBudget)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, "", ctx.Err()
+		}
+		fmt.Printf("⚠️ Ошибка JS-парсинга: %v\n", err)
+		return nil, "⚠️ Page is loading... (JS timed out)", nil
+	}
+
+	jsonString := res.Value.String()
+	if jsonString == "" || jsonString == "null" {
+		return nil, "Page is empty", nil
+	}
+
+	var elements []scannedElement
+	if err := json.Unmarshal([]byte(jsonString), &elements); err != nil {
+		return nil, "", fmt.Errorf("json unmarshal: %w", err)
+	}
+
+	// Cross-origin iframe недоступны из JS страницы — сканируем каждый
+	// в его собственном контексте и продолжаем нумерацию
+	elements = append(elements, s.scanCrossOriginFrames(ctx, s.CurrentPage, len(elements)+1, s.TextBudget-textLength(elements), 0)...)
+	return elements, "", nil
 }
 
 // ⚡ ЛЕНИВЫЙ поиск элемента — только когда нужен клик/ввод
-func (s *BrowserService) GetElement(id int) (*rod.Element, error) {
+func (s *BrowserService) GetElement(ctx context.Context, id int) (*rod.Element, error) {
 	// Проверяем кэш
 	if el, ok := s.ElementMap[id]; ok {
 		return el, nil
 	}
 
-	// Ищем по data-agent-id
-	selector := fmt.Sprintf("[data-agent-id='%d']", id)
-	el, err := s.CurrentPage.Timeout(2 * time.Second).Element(selector)
+	// Ищем по реестру из ObserveElementsScript: CSS-селектор по data-agent-id
+	// не проходит внутрь shadow root и iframe. Элементы cross-origin фреймов
+	// живут в реестре своего фрейма.
+	page, origin := s.pageFor(id)
+	el, err := page.Context(ctx).Timeout(2 * time.Second).ElementByJS(rod.Eval(FindElementScript, id))
 	if err != nil {
+		if origin != "" {
+			return nil, fmt.Errorf("element %d not found in frame %s: %w", id, origin, err)
+		}
 		return nil, fmt.Errorf("element %d not found: %w", id, err)
 	}
 
@@ -129,18 +230,3 @@ func (s *BrowserService) GetElement(id int) (*rod.Element, error) {
 	s.ElementMap[id] = el
 	return el, nil
 }
-
-func tryWaitStable(page *rod.Page, timeout time.Duration) {
-	done := make(chan struct{})
-	go func() {
-		page.Timeout(timeout).WaitStable(500 * time.Millisecond)
-		close(done)
-	}()
-
-	select {
-	case <-done:
-		return
-	case <-time.After(timeout):
-		return
-	}
-}
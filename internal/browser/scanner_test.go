@@ -0,0 +1,204 @@
+package browser
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"browser-agent/internal/entity"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// go test ./internal/browser -run TestObserve -update — перезаписать .golden
+var update = flag.Bool("update", false, "rewrite scanner golden files")
+
+var summaryID = regexp.MustCompile(`(?m)^\[(\d+)\]`)
+
+// newTestService поднимает headless Chromium без stealth и без общего user_data.
+// Если браузера в системе нет — тест пропускается.
+func newTestService(t *testing.T) *BrowserService {
+	t.Helper()
+
+	bin, ok := launcher.LookPath()
+	if !ok {
+		t.Skip("Chromium не найден, пропускаю тесты сканера")
+	}
+
+	controlURL, err := launcher.New().
+		Bin(bin).
+		Headless(true).
+		UserDataDir(t.TempDir()).
+		Launch()
+	if err != nil {
+		t.Skipf("не удалось запустить браузер: %v", err)
+	}
+
+	browser := rod.New().ControlURL(controlURL)
+	if err := browser.Connect(); err != nil {
+		t.Fatalf("не удалось подключиться: %v", err)
+	}
+	t.Cleanup(func() { _ = browser.Close() })
+
+	page, err := browser.Page(proto.TargetCreateTarget{})
+	if err != nil {
+		t.Fatalf("не удалось открыть вкладку: %v", err)
+	}
+
+	return &BrowserService{
+		browser:     browser,
+		CurrentPage: page,
+		ElementMap:  make(map[int]*rod.Element),
+	}
+}
+
+// fixtureServer раздает testdata/ до конца теста. В mux можно добавить свои
+// ответы — например, медленный API.
+func fixtureServer(t *testing.T) (string, *http.ServeMux) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.Dir("testdata")))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv.URL, mux
+}
+
+// openFixture открывает страницу и делает первое наблюдение — ID в тестах
+// берутся из этой сводки
+func openFixture(t *testing.T, s *BrowserService, url string) (context.Context, *entity.BrowserState) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	t.Cleanup(cancel)
+
+	if err := s.Navigate(ctx, url); err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+	state, err := s.Observe(ctx)
+	if err != nil {
+		t.Fatalf("Observe failed: %v", err)
+	}
+	return ctx, state
+}
+
+// Каждая фикстура в testdata/<name>.html проверяет один класс элементов
+// ObserveElementsScript; ожидаемый DOMSummary лежит рядом в <name>.golden.
+func TestObserve_Fixtures(t *testing.T) {
+	s := newTestService(t)
+	base, _ := fixtureServer(t)
+
+	fixtures := []string{"forms", "form_controls", "contenteditable", "checkboxes", "links", "clickable", "frames", "cross_origin"}
+
+	// Порт httptest случайный — в эталонах вместо него PORT (origin фреймов)
+	port := base[strings.LastIndex(base, ":")+1:]
+
+	for _, name := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			ctx, state := openFixture(t, s, base+"/"+name+".html")
+			summary := strings.ReplaceAll(state.DOMSummary, ":"+port, ":PORT")
+
+			golden := filepath.Join("testdata", name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, []byte(summary), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden: %v", err)
+			}
+			if summary != string(want) {
+				t.Errorf("DOMSummary mismatch for %s.html\n--- got ---\n%s--- want ---\n%s", name, summary, want)
+			}
+
+			// Каждый ID из сводки должен вести к реальному элементу страницы
+			for _, m := range summaryID.FindAllStringSubmatch(state.DOMSummary, -1) {
+				id, _ := strconv.Atoi(m[1])
+				if _, err := s.GetElement(ctx, id); err != nil {
+					t.Errorf("GetElement(%d): %v", id, err)
+				}
+			}
+		})
+	}
+}
+
+// ID элементов из shadow root и фреймов должны вести к ним же при вводе
+func TestObserve_TypeIntoShadowAndFrames(t *testing.T) {
+	s := newTestService(t)
+	base, _ := fixtureServer(t)
+
+	// ID — по эталонам frames.golden и cross_origin.golden
+	cases := []struct {
+		name string
+		page string
+		id   int
+		text string
+	}{
+		{"shadow root", "frames.html", 2, "4242"},
+		{"same-origin iframe", "frames.html", 4, "admin"},
+		{"cross-origin iframe", "cross_origin.html", 2, "guest"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, _ := openFixture(t, s, base+"/"+tc.page)
+			if err := s.Type(ctx, tc.id, tc.text); err != nil {
+				t.Fatalf("Type(%d) failed: %v", tc.id, err)
+			}
+			el, err := s.GetElement(ctx, tc.id)
+			if err != nil {
+				t.Fatalf("GetElement(%d): %v", tc.id, err)
+			}
+			if got := el.MustProperty("value").String(); got != tc.text {
+				t.Errorf("Element %d value: got %q, want %q", tc.id, got, tc.text)
+			}
+		})
+	}
+}
+
+// Текст страницы идет строками без ID вперемешку с элементами и укладывается в TextBudget
+func TestObserve_TextBlocks(t *testing.T) {
+	s := newTestService(t)
+	s.TextBudget = 3000
+
+	base, _ := fixtureServer(t)
+	ctx, state := openFixture(t, s, base+"/text.html")
+
+	golden := filepath.Join("testdata", "text.golden")
+	if *update {
+		if err := os.WriteFile(golden, []byte(state.DOMSummary), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	} else if want, err := os.ReadFile(golden); err != nil {
+		t.Fatalf("read golden: %v", err)
+	} else if state.DOMSummary != string(want) {
+		t.Errorf("DOMSummary mismatch for text.html\n--- got ---\n%s--- want ---\n%s", state.DOMSummary, want)
+	}
+
+	// Маленький бюджет: заголовок целиком, абзац обрезан, дальше текста нет
+	s.TextBudget = 20
+	state, err := s.Observe(ctx)
+	if err != nil {
+		t.Fatalf("Observe failed: %v", err)
+	}
+	if !strings.Contains(state.DOMSummary, "    <p> Доставка за…\n") || strings.Contains(state.DOMSummary, "<td>") {
+		t.Errorf("Text budget not applied:\n%s", state.DOMSummary)
+	}
+	if !strings.Contains(state.DOMSummary, "[3] <button> [ACTION] Отменить заказ\n") {
+		t.Errorf("Elements must not depend on text budget:\n%s", state.DOMSummary)
+	}
+}
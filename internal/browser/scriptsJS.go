@@ -1,45 +1,204 @@
 package browser
 
 // JS script to highlight clicked elements
-const HighlightClickScript = `(e) => e.style.border = "3px solid #00FF00"`
+const HighlightClickScript = `() => { this.style.border = "3px solid #00FF00" }`
 
 // JS script to highlight typed elements
-const HighlightTypeScript = `(e) => e.style.border = "3px solid blue"`
+const HighlightTypeScript = `() => { this.style.border = "3px solid blue" }`
+
+// JS script to highlight hovered elements
+const HighlightHoverScript = `() => { this.style.outline = "3px dotted #B040FF" }`
 
 const ScrollDownScript = `() => { window.scrollBy(0, window.innerHeight * 0.7); return true; }`
 
 const ScrollUpScript = `() => { window.scrollBy(0, -window.innerHeight * 0.7); return true; }`
 
-const ObserveElementsScript = `function() {
-    const MAX_ITEMS = 600;
+// domPathJS — общая для сканеров функция domPath(el), часть отпечатка элемента
+const domPathJS = `// Путь tag:n (n — номер среди соседей с тем же тегом) от корня документа,
+// с переходами через shadow root и same-origin iframe
+function domPath(el) {
+    const parts = [];
+    let node = el;
+    while (node && node.nodeType === 1) {
+        let n = 1;
+        for (let sib = node.previousElementSibling; sib; sib = sib.previousElementSibling) {
+            if (sib.tagName === node.tagName) n++;
+        }
+        parts.unshift(node.tagName.toLowerCase() + ':' + n);
+
+        if (node.parentElement) { node = node.parentElement; continue; }
+        const root = node.getRootNode();
+        if (root && root.host) { parts.unshift('#shadow'); node = root.host; continue; }
+        const frame = node.ownerDocument.defaultView.frameElement;
+        if (frame) { parts.unshift('#frame'); node = frame; continue; }
+        break;
+    }
+    return parts.join('/');
+}`
+
+// viewportJS — координаты элемента в верхнем окне (элементы iframe сдвигаются
+// на позицию фрейма) и положение относительно вьюпорта: in / above / below
+const viewportJS = `function viewportRect(el) {
+    const r = el.getBoundingClientRect();
+    let left = r.left, top = r.top;
+    let win = el.ownerDocument.defaultView;
+    while (win && win.frameElement) {
+        const frame = win.frameElement;
+        const fr = frame.getBoundingClientRect();
+        left += fr.left + frame.clientLeft;
+        top += fr.top + frame.clientTop;
+        win = win.parent;
+    }
+    return { left, top, width: r.width, height: r.height, right: left + r.width, bottom: top + r.height };
+}
+function viewportOf(el) {
+    const r = viewportRect(el);
+    // Скрытый элемент (file input под стилизованной кнопкой) нигде не лежит
+    if (r.width === 0 && r.height === 0) return 'in';
+    if (r.bottom <= 0) return 'above';
+    if (r.top >= window.innerHeight) return 'below';
+    return 'in';
+}`
+
+// formControlJS — поля, в которые нельзя просто ввести текст: formControl(el)
+// возвращает { tag, text } для <select>, даты/времени, ползунка и файла, иначе null.
+// Общий для JS-сканера и AX-наблюдателя, чтобы select_option и set_value находили
+// [CHOOSE] и [RANGE] при любом OBSERVER.
+const formControlJS = `// Сколько вариантов <select> перечислять в строке; остальные — "+N more"
+    const MAX_OPTIONS = 15;
+    // Формат значения для полей даты и времени — его ждет set_value
+    const DATE_FORMATS = { 'date': 'YYYY-MM-DD', 'time': 'HH:MM', 'datetime-local': 'YYYY-MM-DDTHH:MM',
+        'month': 'YYYY-MM', 'week': 'YYYY-Www' };
+
+    // Подпись поля формы: label, aria-label, затем name
+    function fieldLabel(el) {
+        let t = (el.labels && el.labels.length > 0) ? el.labels[0].innerText : '';
+        if (!t) t = el.getAttribute('aria-label') || el.getAttribute('title') || el.getAttribute('name') || '';
+        return t.replace(/\s+/g, ' ').trim().substring(0, 40);
+    }
+
+    function formControl(el) {
+        const tagName = el.tagName.toLowerCase();
+        if (tagName === 'select') {
+            const opts = Array.from(el.options).map(o => (o.label || o.text || '').replace(/\s+/g, ' ').trim().substring(0, 30));
+            const chosen = Array.from(el.selectedOptions).map(o => (o.label || o.text || '').replace(/\s+/g, ' ').trim().substring(0, 30));
+            let list = opts.slice(0, MAX_OPTIONS).join(' | ');
+            if (opts.length > MAX_OPTIONS) list += ' | +' + (opts.length - MAX_OPTIONS) + ' more';
+            return { tag: 'select', text: "[CHOOSE] " + (fieldLabel(el) || "Select") + ": " + (chosen.join(', ') || "—") + " {" + list + "}" };
+        }
+        if (tagName !== 'input') return null;
+        if (DATE_FORMATS[el.type]) {
+            return { tag: 'date', text: "[DATE] " + (fieldLabel(el) || "Date") + " = " + (el.value || "empty") + " (" + DATE_FORMATS[el.type] + ")" };
+        }
+        if (el.type === 'range') {
+            const range = (el.min || '0') + ".." + (el.max || '100') + (el.step && el.step !== '1' ? ", step " + el.step : "");
+            return { tag: 'range', text: "[RANGE] " + (fieldLabel(el) || "Slider") + " = " + el.value + " (" + range + ")" };
+        }
+        if (el.type === 'file') {
+            let t = "[FILE] " + (fieldLabel(el) || "File");
+            if (el.accept) t += " (accept: " + el.accept + ")";
+            if (el.files && el.files.length > 0) t += " (attached: " + Array.from(el.files).map(f => f.name).join(', ') + ")";
+            return { tag: 'file', text: t };
+        }
+        return null;
+    }`
+
+// textBlockJS — blockText(el): текст блока (заголовка, абзаца, ячейки) для
+// DOMSummary, обрезанный до MAX_BLOCK; пустая строка — выводить нечего. Общий для JS-сканера
+// и AX-наблюдателя, чтобы TEXT_BUDGET работал при любом OBSERVER.
+const textBlockJS = `// Один блок текста не длиннее этого — длинная статья не съедает весь бюджет
+    const MAX_BLOCK = 300;
+
+    function blockText(el) {
+        let t = (el.innerText || "").replace(/\s+/g, " ").trim();
+        if (t.length < 2) return '';
+        // Пункт меню из одной ссылки: ее текст и так будет в строке ссылки
+        const inner = el.querySelector('a, button, input, select, textarea, [role="button"]');
+        if (inner && (inner.innerText || "").replace(/\s+/g, " ").trim() === t) return '';
+        if (t.length > MAX_BLOCK) t = t.substring(0, MAX_BLOCK) + "…";
+        return t;
+    }`
+
+// ObserveElementsScript нумерует элементы начиная со startId: так ID основной
+// страницы и cross-origin фреймов (у каждого свой запуск) не пересекаются.
+// textBudget — сколько символов видимого текста (заголовки, абзацы, ячейки)
+// вставить между элементами; 0 — только интерактивные элементы.
+const ObserveElementsScript = `function(startId, textBudget) {
+    // Предохранитель от гигантских страниц. Обычный лимит (maxElements) Go
+    // применяет сам, оставляя в первую очередь элементы во вьюпорте.
+    const MAX_ITEMS = 3000;
+    const TEXT_TAGS = new Set(['h1', 'h2', 'h3', 'h4', 'h5', 'h6', 'p', 'li', 'td', 'th', 'dt', 'dd',
+        'blockquote', 'pre', 'caption', 'figcaption']);
 
     // --- 1. ОЧИСТКА ---
+    // querySelectorAll не видит shadow root и iframe — старые метки снимаем по реестру
     document.querySelectorAll('[data-agent-id]').forEach(el => el.removeAttribute('data-agent-id'));
+    (window.__agentElements || []).forEach(el => el && el.removeAttribute('data-agent-id'));
     const oldContainer = document.getElementById('agent-ids-overlay');
     if (oldContainer) oldContainer.remove();
 
     const items = [];
-    let idCounter = 1;
+    let idCounter = startId || 1;
     const seen = new Set();
+    // Блоки текста уже в выдаче: их потомки повторили бы тот же текст
+    const textBlocks = new Set();
+    let textLeft = textBudget || 0;
+
+    // Реестр ID -> элемент: по нему GetElement находит элементы из shadow DOM и фреймов
+    const registry = [null];
+    window.__agentElements = registry;
+
+    function register(el) {
+        seen.add(el);
+        const id = idCounter++;
+        el.setAttribute('data-agent-id', String(id));
+        registry[id] = el;
+        return id;
+    }
+
+    // Стиль считаем окном того документа, где живет элемент (iframe — свое окно)
+    function styleOf(el) {
+        return el.ownerDocument.defaultView.getComputedStyle(el);
+    }
 
     function isVisible(el) {
         const rect = el.getBoundingClientRect();
         if (rect.width < 1 || rect.height < 1) return false;
-        const style = window.getComputedStyle(el);
+        const style = styleOf(el);
         return style.visibility !== 'hidden' && style.display !== 'none' && style.opacity !== '0';
     }
 
-    const all = document.body.querySelectorAll('*');
+    // Обход в порядке документа с заходом в открытые shadow root и same-origin iframe.
+    // body фрейма тоже кандидат: встроенные редакторы делают его contenteditable.
+    function collect(root, out) {
+        for (const el of root.querySelectorAll('*')) {
+            out.push(el);
+            if (el.shadowRoot) collect(el.shadowRoot, out);
+            if (el.tagName === 'IFRAME' || el.tagName === 'FRAME') {
+                let doc = null;
+                try { doc = el.contentDocument; } catch (e) { /* cross-origin */ }
+                if (doc && doc.body) {
+                    out.push(doc.body);
+                    collect(doc.body, out);
+                }
+            }
+        }
+        return out;
+    }
+
+    const all = collect(document.body, []);
     
     for (const el of all) {
-        if (items.length >= MAX_ITEMS) break;
+        if (idCounter - (startId || 1) >= MAX_ITEMS) break;
         if (seen.has(el)) continue;
-        if (!isVisible(el)) continue;
+        // Файловый input часто спрятан под стилизованной кнопкой, но файл в него
+        // прикрепляется и скрытым — кнопка же откроет системный диалог
+        if (!isVisible(el) && !(el.tagName === 'INPUT' && el.type === 'file')) continue;
 
         const tagName = el.tagName.toLowerCase();
         const role = el.getAttribute('role');
         const className = (el.className && typeof el.className === 'string') ? el.className.toLowerCase() : "";
-        const style = window.getComputedStyle(el);
+        const style = styleOf(el);
         const isClickableStyle = style.cursor === 'pointer';
 
         // =================================================================
@@ -54,9 +213,7 @@ const ObserveElementsScript = `function() {
             // Пропускаем, если родитель уже был добавлен как инпут (чтобы не дублировать)
             if (el.parentElement && seen.has(el.parentElement)) continue;
 
-            seen.add(el);
-            const id = idCounter++;
-            el.setAttribute('data-agent-id', String(id));
+            const id = register(el);
 
             // Пытаемся найти текст плейсхолдера
             let t = el.innerText || el.getAttribute('aria-label') || el.getAttribute('placeholder') || "";
@@ -80,11 +237,17 @@ const ObserveElementsScript = `function() {
         // =================================================================
         // 1. INPUTS & TEXTAREAS (Стандартные)
         // =================================================================
+        // [CHOOSE], [DATE], [RANGE], [FILE] — общий с AX-наблюдателем формат
+        const control = formControl(el);
+        if (control) {
+            const id = register(el);
+            items.push({ id, tag: control.tag, text: control.text, interactive: true });
+            continue;
+        }
+
         if (tagName === 'input' || tagName === 'textarea') {
-            seen.add(el);
-            const id = idCounter++;
-            el.setAttribute('data-agent-id', String(id));
-            
+            const id = register(el);
+
             if (el.type === 'checkbox' || el.type === 'radio') {
                 let label = "";
                 if (el.labels && el.labels.length > 0) label = el.labels[0].innerText;
@@ -104,9 +267,7 @@ const ObserveElementsScript = `function() {
         // =================================================================
         const isLikelyCheckbox = className.includes('checkbox') || role === 'checkbox' || role === 'radio';
         if (isLikelyCheckbox && !el.querySelector('input')) {
-            seen.add(el);
-            const id = idCounter++;
-            el.setAttribute('data-agent-id', String(id));
+            const id = register(el);
             const isSelected = className.includes('active') || className.includes('checked') || el.getAttribute('aria-checked') === 'true';
             const state = isSelected ? ' [V]' : ' [ ]';
             let t = (el.innerText || "").replace(/[\n\r]+/g, " ").trim().substring(0, 50);
@@ -122,9 +283,7 @@ const ObserveElementsScript = `function() {
             // Разрешаем ссылки без href, если они кликабельны (SPA навигация)
             if (!href && !el.getAttribute('onclick') && !role && !isClickableStyle) continue;
             
-            seen.add(el);
-            const id = idCounter++;
-            el.setAttribute('data-agent-id', String(id));
+            const id = register(el);
             
             let t = el.innerText || el.getAttribute('aria-label') || el.getAttribute('title') || "";
             if (!t) {
@@ -140,18 +299,17 @@ const ObserveElementsScript = `function() {
         // 4. КНОПКИ
         // =================================================================
         if (tagName === 'button' || role === 'button') {
-            seen.add(el);
-            const id = idCounter++;
-            el.setAttribute('data-agent-id', String(id));
+            const id = register(el);
             let t = (el.innerText || el.getAttribute('aria-label') || "Button").replace(/[\n\r]+/g, " ").trim().substring(0, 50);
             items.push({ id, tag: 'button', text: "[ACTION] " + t, interactive: true });
             continue;
         }
 
         // =================================================================
-        // 5. ПРОЧИЕ КЛИКАБЕЛЬНЫЕ (div, span, img)
+        // 5. ПРОЧИЕ КЛИКАБЕЛЬНЫЕ (div, span, img) и перетаскиваемые карточки
         // =================================================================
-        if ((tagName === 'div' || tagName === 'span' || tagName === 'li' || tagName === 'img' || tagName === 'svg') && isClickableStyle) {
+        const isDraggable = el.getAttribute('draggable') === 'true';
+        if ((tagName === 'div' || tagName === 'span' || tagName === 'li' || tagName === 'img' || tagName === 'svg') && (isClickableStyle || isDraggable)) {
              const rect = el.getBoundingClientRect();
              if (rect.width > 500 && rect.height > 500) continue; 
              
@@ -164,15 +322,508 @@ const ObserveElementsScript = `function() {
              }
              if (parentFound) continue;
 
-             seen.add(el);
-             const id = idCounter++;
-             el.setAttribute('data-agent-id', String(id));
+             const id = register(el);
 
              let t = el.innerText || el.getAttribute('alt') || "";
              t = t.replace(/[\n\r]+/g, " ").trim().substring(0, 40);
-             items.push({ id, tag: 'clickable', text: "[CLICK] " + (t || "Item"), interactive: true });
+             if (isDraggable) {
+                 items.push({ id, tag: 'draggable', text: "[DRAG] " + (t || "Item"), interactive: true });
+             } else {
+                 items.push({ id, tag: 'clickable', text: "[CLICK] " + (t || "Item"), interactive: true });
+             }
+             continue;
         }
+
+        // =================================================================
+        // 6. ТЕКСТ (заголовки, абзацы, ячейки, пункты списков) — без ID.
+        // div и span — только листовые: цена или строка письма часто лежит прямо в них.
+        // =================================================================
+        if (textLeft > 0 && (TEXT_TAGS.has(tagName) || ((tagName === 'div' || tagName === 'span') && el.childElementCount === 0))) {
+            if (insideText(el)) continue;
+
+            let t = blockText(el);
+            if (!t) continue;
+            if (t.length > textLeft) t = t.substring(0, textLeft) + "…";
+            textLeft -= t.length;
+
+            textBlocks.add(el);
+            items.push({ id: 0, tag: tagName, text: t, interactive: false, el });
+        }
+    }
+
+    // Текст внутри уже выданного блока или кнопки/ссылки — повтор
+    function insideText(el) {
+        for (let p = el.parentElement; p; p = p.parentElement) {
+            if (textBlocks.has(p) || seen.has(p)) return true;
+        }
+        return false;
+    }
+
+    ` + viewportJS + `
+    ` + formControlJS + `
+    ` + textBlockJS + `
+
+    // --- ОТПЕЧАТОК для стабильных ID (сами ID назначает Go, см. RemapIDsScript) ---
+    // Имя без значения поля: после ввода текста ID не должен меняться
+    function accessibleName(el) {
+        let n = el.getAttribute('aria-label') || '';
+        if (!n && el.labels && el.labels.length > 0) n = el.labels[0].innerText;
+        if (!n) n = el.getAttribute('placeholder') || el.getAttribute('alt') || el.getAttribute('title') || '';
+        if (!n && (el.type === 'submit' || el.type === 'button')) n = el.value || '';
+        if (!n && el.tagName !== 'INPUT' && el.tagName !== 'TEXTAREA' && el.tagName !== 'SELECT' && !el.isContentEditable) n = el.innerText || '';
+        return n.replace(/\s+/g, ' ').trim().substring(0, 50);
+    }
+
+    ` + domPathJS + `
+
+    for (const item of items) {
+        if (!item.interactive) {
+            item.viewport = viewportOf(item.el);
+            delete item.el;
+            continue;
+        }
+        const el = registry[item.id];
+        item.viewport = viewportOf(el);
+        item.role = el.getAttribute('role') || '';
+        item.name = accessibleName(el);
+        item.path = domPath(el);
     }
 
     return JSON.stringify(items);
 }`
+
+// CrossOriginFramesScript — видимые iframe, чей документ недоступен из JS страницы
+// (включая вложенные в same-origin фреймы и shadow root)
+const CrossOriginFramesScript = `() => {
+    const frames = [];
+    function walk(root) {
+        for (const el of root.querySelectorAll('*')) {
+            if (el.shadowRoot) walk(el.shadowRoot);
+            if (el.tagName !== 'IFRAME' && el.tagName !== 'FRAME') continue;
+
+            let doc = null;
+            try { doc = el.contentDocument; } catch (e) { /* cross-origin */ }
+            if (doc && doc.body) {
+                walk(doc.body);
+                continue;
+            }
+            const rect = el.getBoundingClientRect();
+            if (rect.width >= 1 && rect.height >= 1) frames.push(el);
+        }
+    }
+    if (document.body) walk(document.body);
+    return frames;
+}`
+
+// ViewportPositionScript — положение элемента (this) относительно вьюпорта его окна
+const ViewportPositionScript = `function() {
+    ` + viewportJS + `
+    return viewportOf(this);
+}`
+
+// RemapIDsScript заменяет временные ID сканера на стабильные: pairs — [[tmp, id], ...].
+// Элементы без пары (отброшенные лимитом) выпадают из реестра.
+const RemapIDsScript = `(pairs) => {
+    const old = window.__agentElements || [];
+    old.forEach(el => el && el.removeAttribute('data-agent-id'));
+    const registry = [];
+    for (const [tmp, id] of pairs) {
+        const el = old[tmp];
+        if (!el) continue;
+        el.setAttribute('data-agent-id', String(id));
+        registry[id] = el;
+    }
+    window.__agentElements = registry;
+    return true;
+}`
+
+// RegisterElementsScript — реестр для AX-наблюдателя: узлы приходят аргументами
+// (по backendNodeId из дерева доступности) в порядке документа. Интерактивные
+// (interactive[i]) получают временные ID 1..N, как у ObserveElementsScript, путь
+// для отпечатка и строку поля формы (formControl), если это оно. Блоки текста
+// получают тег и текст по тем же правилам и в том же textBudget, что у JS-сканера.
+const RegisterElementsScript = `function(textBudget, interactive, ...els) {
+    (window.__agentElements || []).forEach(el => el && el.removeAttribute('data-agent-id'));
+    const old = document.getElementById('agent-ids-overlay');
+    if (old) old.remove();
+
+    const registry = [null];
+    window.__agentElements = registry;
+
+    ` + domPathJS + `
+    ` + viewportJS + `
+    ` + formControlJS + `
+    ` + textBlockJS + `
+
+    let textLeft = textBudget || 0;
+    return els.map((el, i) => {
+        if (!interactive[i]) {
+            let t = textLeft > 0 ? blockText(el) : '';
+            if (t.length > textLeft) t = t.substring(0, textLeft) + "…";
+            textLeft -= t.length;
+            return { tag: el.tagName.toLowerCase(), text: t, viewport: viewportOf(el) };
+        }
+        registry.push(el);
+        el.setAttribute('data-agent-id', String(registry.length - 1));
+        return { path: domPath(el), viewport: viewportOf(el), control: formControl(el) };
+    });
+}`
+
+// FindElementScript — элемент по ID из реестра последнего Observe
+// (null, если его нет или он уже удален из DOM)
+const FindElementScript = `(id) => {
+    const el = window.__agentElements && window.__agentElements[id];
+    return el && el.isConnected ? el : null;
+}`
+
+// DrawMarksScript — set-of-marks для скриншота: рамка и номер поверх каждого
+// элемента с data-agent-id, видимого во вьюпорте. Возвращает число меток.
+const DrawMarksScript = `function() {
+    const old = document.getElementById('agent-ids-overlay');
+    if (old) old.remove();
+
+    const container = document.createElement('div');
+    container.id = 'agent-ids-overlay';
+    container.style.cssText = 'position:fixed;left:0;top:0;width:0;height:0;z-index:2147483647;pointer-events:none;';
+
+    const colors = ['#e6194b', '#3cb44b', '#4363d8', '#f58231', '#911eb4', '#008080', '#9a6324', '#800000'];
+    let count = 0;
+
+    // Координаты элемента внутри iframe переводим в координаты верхнего окна
+    ` + viewportJS + `
+
+    // Реестр из ObserveElementsScript включает элементы shadow DOM и фреймов
+    (window.__agentElements || []).forEach((el, id) => {
+        if (!el || !el.isConnected) return;
+        const rect = viewportRect(el);
+        if (rect.width < 1 || rect.height < 1) return;
+        if (rect.bottom < 0 || rect.right < 0 || rect.top > window.innerHeight || rect.left > window.innerWidth) return;
+
+        const color = colors[Number(id) % colors.length];
+
+        const box = document.createElement('div');
+        box.style.cssText = 'position:fixed;box-sizing:border-box;border:2px solid ' + color + ';' +
+            'left:' + rect.left + 'px;top:' + rect.top + 'px;width:' + rect.width + 'px;height:' + rect.height + 'px;';
+
+        const label = document.createElement('div');
+        label.textContent = String(id);
+        label.style.cssText = 'position:absolute;left:-2px;top:-16px;padding:0 3px;font:bold 12px/14px monospace;' +
+            'color:#fff;background:' + color + ';';
+        if (rect.top < 16) label.style.top = '0px';
+
+        box.appendChild(label);
+        container.appendChild(box);
+        count++;
+    });
+
+    document.documentElement.appendChild(container);
+    return count;
+}`
+
+// ClearMarksScript убирает метки после снимка, чтобы они не мешали кликам и read_text
+const ClearMarksScript = `() => { const o = document.getElementById('agent-ids-overlay'); if (o) o.remove(); return true; }`
+
+// extractHelpersJS — общие для извлечения таблиц и списков функции
+const extractHelpersJS = `const CELL = 200;
+    const clean = (s, n) => (s || '').replace(/\s+/g, ' ').trim().substring(0, n || CELL);
+    const shown = el => el.getClientRects().length > 0;
+    // Элемент, на котором вызван скрипт (по ID), или null — тогда ищем по всей странице
+    const start = (this && this.nodeType === 1) ? this : null;`
+
+// ExtractTableScript превращает таблицу в {columns, rows, total}. Таблица — та,
+// внутри которой элемент (или первая внутри него); без элемента — самая длинная
+// видимая таблица страницы. Заголовки берутся из thead или первой строки из th.
+const ExtractTableScript = `function(maxRows) {
+    ` + extractHelpersJS + `
+
+    let table = null;
+    if (start) {
+        table = start.closest('table') || start.querySelector('table');
+    } else {
+        for (const t of document.querySelectorAll('table')) {
+            if (shown(t) && (!table || t.rows.length > table.rows.length)) table = t;
+        }
+    }
+    if (!table) return null;
+
+    // table.rows — строки только этой таблицы, без вложенных
+    const all = Array.from(table.rows).filter(shown);
+    let header = null;
+    if (table.tHead && table.tHead.rows.length) {
+        header = table.tHead.rows[table.tHead.rows.length - 1];
+    } else if (all.length && Array.from(all[0].cells).every(c => c.tagName === 'TH')) {
+        header = all[0];
+    }
+    const body = all.filter(r => r !== header && !(table.tHead && table.tHead.contains(r)));
+
+    const columns = header ? Array.from(header.cells).map(c => clean(c.innerText)) : [];
+    const width = Math.max(columns.length, ...body.map(r => r.cells.length));
+    const used = new Set();
+    for (let i = 0; i < width; i++) {
+        let name = columns[i] || ('col ' + (i + 1));
+        if (used.has(name)) name += ' (' + (i + 1) + ')';
+        used.add(name);
+        columns[i] = name;
+    }
+
+    const rows = [];
+    let total = 0;
+    for (const r of body) {
+        const cells = Array.from(r.cells).map(c => clean(c.innerText));
+        if (cells.every(c => !c)) continue;
+        total++;
+        if (rows.length >= maxRows) continue;
+        const row = {};
+        cells.forEach((c, i) => { row[columns[i]] = c; });
+        rows.push(row);
+    }
+    return JSON.stringify({ columns, rows, total });
+}`
+
+// ExtractListScript превращает повторяющиеся блоки (пункты списка, карточки
+// товаров, строки выдачи) в {columns: [text, link], rows, total}. Блоки — соседи
+// с тем же тегом и классами: от элемента вверх выбирается уровень с самой
+// большой группой, без элемента — самая большая группа на странице.
+const ExtractListScript = `function(maxRows) {
+    ` + extractHelpersJS + `
+
+    const sig = el => el.tagName + '.' + (typeof el.className === 'string' ? el.className.trim().split(/\s+/).sort().join('.') : '');
+
+    // Самая большая группа одинаковых видимых детей
+    function bestGroup(parent) {
+        const groups = new Map();
+        for (const c of parent.children) {
+            if (!shown(c)) continue;
+            const k = sig(c);
+            if (!groups.has(k)) groups.set(k, []);
+            groups.get(k).push(c);
+        }
+        let best = [];
+        for (const g of groups.values()) if (g.length > best.length) best = g;
+        return best;
+    }
+
+    let items = [];
+    if (start) {
+        for (let node = start; node.parentElement && node !== document.body; node = node.parentElement) {
+            const same = Array.from(node.parentElement.children).filter(c => shown(c) && sig(c) === sig(node));
+            if (same.length >= 2 && same.length > items.length) items = same;
+        }
+        if (items.length < 2) items = bestGroup(start);
+    } else {
+        for (const el of document.body.querySelectorAll('*')) {
+            if (el.children.length <= items.length || !shown(el)) continue;
+            const g = bestGroup(el);
+            if (g.length > items.length) items = g;
+        }
+    }
+    if (items.length < 2) return null;
+
+    const rows = [];
+    for (const item of items.slice(0, maxRows)) {
+        const a = item.tagName === 'A' ? item : item.querySelector('a[href]');
+        rows.push({ text: clean(item.innerText, 300), link: a && a.href ? a.href : '' });
+    }
+    return JSON.stringify({ columns: ['text', 'link'], rows, total: items.length });
+}`
+
+// SelectOptionScript выбирает вариант <select> по видимому тексту: сначала
+// точное совпадение без учета регистра, затем единственное вхождение подстроки.
+// Возвращает {ok, chosen} или {ok:false, reason, options} для сообщения модели.
+const SelectOptionScript = `function(label) {
+    const norm = s => (s || '').replace(/\s+/g, ' ').trim().toLowerCase();
+    if (this.tagName !== 'SELECT') return JSON.stringify({ ok: false, reason: 'not a <select> element' });
+
+    const opts = Array.from(this.options);
+    const texts = opts.map(o => (o.label || o.text || '').replace(/\s+/g, ' ').trim());
+    const want = norm(label);
+    let i = texts.findIndex(t => t.toLowerCase() === want);
+    if (i < 0) {
+        const partial = texts.map((t, n) => t.toLowerCase().includes(want) ? n : -1).filter(n => n >= 0);
+        if (partial.length > 1) return JSON.stringify({ ok: false, reason: 'ambiguous', options: partial.map(n => texts[n]) });
+        if (partial.length === 1) i = partial[0];
+    }
+    if (i < 0) return JSON.stringify({ ok: false, reason: 'no such option', options: texts.slice(0, 30) });
+    if (opts[i].disabled) return JSON.stringify({ ok: false, reason: 'option is disabled' });
+
+    if (this.multiple) opts[i].selected = true; else this.selectedIndex = i;
+    this.dispatchEvent(new Event('input', { bubbles: true }));
+    this.dispatchEvent(new Event('change', { bubbles: true }));
+    return JSON.stringify({ ok: true, chosen: texts[i] });
+}`
+
+// SetValueScript задает значение полю даты, времени или ползунку через нативный
+// сеттер (его перехватывают React и подобные) и шлет input/change. Браузер сам
+// отбрасывает значение не в том формате — тогда возвращается {ok:false}.
+const SetValueScript = `function(value) {
+    const types = ['date', 'time', 'datetime-local', 'month', 'week', 'range', 'color', 'number'];
+    if (this.tagName !== 'INPUT' || !types.includes(this.type)) {
+        return JSON.stringify({ ok: false, reason: 'set_value works only for date, time, range, color and number inputs; use the type tool' });
+    }
+    const setter = Object.getOwnPropertyDescriptor(HTMLInputElement.prototype, 'value').set;
+    setter.call(this, value);
+    this.dispatchEvent(new Event('input', { bubbles: true }));
+    this.dispatchEvent(new Event('change', { bubbles: true }));
+    // range округляет и зажимает значение в min..max — это не ошибка, сообщаем итог
+    if (this.type !== 'range' && this.value !== value) {
+        return JSON.stringify({ ok: false, reason: 'invalid value for ' + this.type + ' input', actual: this.value });
+    }
+    return JSON.stringify({ ok: true, actual: this.value });
+}`
+
+// DispatchMouseEventScript — запасной клик/двойной клик/правый клик/наведение
+// событиями прямо на элементе, когда настоящая мышь до него не дотягивается
+const DispatchMouseEventScript = `function(kind) {
+    const opts = { bubbles: true, cancelable: true, view: window };
+    switch (kind) {
+    case 'click':
+        // Ровно один клик: второй вернул бы чекбокс обратно и дважды вызвал обработчики
+        this.click();
+        break;
+    case 'dblclick':
+        this.dispatchEvent(new MouseEvent('click', { ...opts, detail: 1 }));
+        this.dispatchEvent(new MouseEvent('click', { ...opts, detail: 2 }));
+        this.dispatchEvent(new MouseEvent('dblclick', { ...opts, detail: 2 }));
+        break;
+    case 'contextmenu':
+        this.dispatchEvent(new MouseEvent('contextmenu', { ...opts, button: 2, buttons: 2 }));
+        break;
+    case 'hover':
+        this.dispatchEvent(new MouseEvent('mouseover', opts));
+        this.dispatchEvent(new MouseEvent('mouseenter', { ...opts, bubbles: false }));
+        this.dispatchEvent(new MouseEvent('mousemove', opts));
+        break;
+    }
+    return true;
+}`
+
+// DragAndDropScript — HTML5 drag-and-drop с элемента на target: события drag*
+// с общим DataTransfer, как при настоящем перетаскивании
+const DragAndDropScript = `function(target) {
+    const dt = new DataTransfer();
+    const fire = (el, type) => el.dispatchEvent(new DragEvent(type, { bubbles: true, cancelable: true, dataTransfer: dt }));
+    fire(this, 'dragstart');
+    fire(target, 'dragenter');
+    fire(target, 'dragover');
+    fire(target, 'drop');
+    fire(this, 'dragend');
+    return true;
+}`
+
+// mutationCounterJS ставит на страницу один MutationObserver (один раз): счетчик
+// изменений DOM для проверки действий и время последнего изменения для ожидания тишины
+const mutationCounterJS = `
+    if (!window.__agentMutations) {
+        const counter = { n: 0, last: performance.now() };
+        new MutationObserver(list => {
+            for (const m of list) {
+                // Метки сканера — не реакция страницы
+                if (m.type === 'attributes' && m.attributeName === 'data-agent-id') continue;
+                counter.n++;
+                counter.last = performance.now();
+            }
+        }).observe(document.documentElement, { subtree: true, childList: true, attributes: true, characterData: true });
+        window.__agentMutations = counter;
+    }
+`
+
+// ProbeMutationsScript возвращает счетчик изменений DOM: если между двумя
+// вызовами он сдвинулся, страница отреагировала на действие
+const ProbeMutationsScript = `() => {` + mutationCounterJS + `
+    return window.__agentMutations.n;
+}`
+
+// DOMQuietScript ждет, пока DOM не будет меняться quietMs подряд (не дольше timeoutMs).
+// Тишина считается от последнего изменения, а не от вызова: на уже спокойной
+// странице повторное ожидание завершается сразу.
+const DOMQuietScript = `(quietMs, timeoutMs) => {` + mutationCounterJS + `
+    const counter = window.__agentMutations;
+    const start = performance.now();
+    return new Promise(resolve => {
+        const check = () => {
+            const now = performance.now();
+            const idle = now - counter.last;
+            if (idle >= quietMs) return resolve(true);
+            if (now - start >= timeoutMs) return resolve(false);
+            setTimeout(check, Math.min(quietMs - idle, timeoutMs - (now - start)) + 5);
+        };
+        check();
+    });
+}`
+
+// WaitTextScript ждет, пока видимый текст страницы не начнет (gone = false) или
+// не перестанет (gone = true) содержать text, без учета регистра. Проверка — на
+// изменениях DOM, не чаще раза в 100 мс: innerText пересчитывает раскладку.
+const WaitTextScript = `(text, gone, timeoutMs) => new Promise(resolve => {
+    const needle = text.toLowerCase();
+    const met = () => ((document.body && document.body.innerText) || '').toLowerCase().includes(needle) !== gone;
+    if (met()) return resolve(true);
+
+    let pending = false;
+    const observer = new MutationObserver(() => {
+        if (pending) return;
+        pending = true;
+        setTimeout(() => { pending = false; if (met()) finish(true); }, 100);
+    });
+    const timer = setTimeout(() => finish(met()), timeoutMs);
+    function finish(ok) {
+        observer.disconnect();
+        clearTimeout(timer);
+        resolve(ok);
+    }
+    observer.observe(document.documentElement, { subtree: true, childList: true, attributes: true, characterData: true });
+})`
+
+// ProbeElementScript — значение и состояние элемента для проверки click/type и
+// перекрытие: что окажется под курсором в центре элемента. Элемент за экраном
+// не перекрыт — проверить нечем, клик сам прокручивает к нему.
+const ProbeElementScript = `() => {
+    const el = this;
+    const r = el.getBoundingClientRect();
+    const cx = r.left + r.width / 2, cy = r.top + r.height / 2;
+    let obscured = false;
+    if (r.width > 0 && r.height > 0 && cx >= 0 && cy >= 0 && cx <= window.innerWidth && cy <= window.innerHeight) {
+        const root = el.getRootNode();
+        const hit = (root.elementFromPoint ? root : document).elementFromPoint(cx, cy);
+        obscured = !!hit && !(hit === el || el.contains(hit) || hit.contains(el));
+    }
+    let value = '';
+    if (el.tagName === 'INPUT' || el.tagName === 'TEXTAREA' || el.tagName === 'SELECT') value = el.value;
+    else if (el.isContentEditable) value = el.innerText;
+    const state = [el.checked, el.open, el.disabled, el.getAttribute('aria-expanded'), el.getAttribute('aria-pressed'),
+        el.getAttribute('aria-checked'), el.getAttribute('aria-selected'), el.className].join('|');
+    return JSON.stringify({ found: el.isConnected, obscured, value, state });
+}`
+
+// FocusSelectScript фокусирует поле и выделяет его содержимое, чтобы вставленный
+// следом текст заменил старый
+const FocusSelectScript = `() => {
+    this.focus();
+    if (typeof this.select === 'function') {
+        this.select();
+    } else if (this.isContentEditable) {
+        const range = document.createRange();
+        range.selectNodeContents(this);
+        const sel = window.getSelection();
+        sel.removeAllRanges();
+        sel.addRange(range);
+    }
+    return true;
+}`
+
+// SetTextValueScript — запасной ввод: value через нативный сеттер (его
+// перехватывают React и подобные) или текст contenteditable, затем input/change
+const SetTextValueScript = `function(text) {
+    if (this.isContentEditable) {
+        this.focus();
+        this.textContent = text;
+        this.dispatchEvent(new InputEvent('input', { bubbles: true, inputType: 'insertText', data: text }));
+        return true;
+    }
+    const proto = this.tagName === 'TEXTAREA' ? HTMLTextAreaElement.prototype : HTMLInputElement.prototype;
+    const setter = Object.getOwnPropertyDescriptor(proto, 'value').set;
+    setter.call(this, text);
+    this.dispatchEvent(new Event('input', { bubbles: true }));
+    this.dispatchEvent(new Event('change', { bubbles: true }));
+    return true;
+}`
@@ -17,6 +17,25 @@ type BrowserService struct {
 	browser     *rod.Browser
 	CurrentPage *rod.Page            // Текущая активная вкладка
 	ElementMap  map[int]*rod.Element // Карта ID -> Элемент (для кликов)
+
+	// Vision — прикладывать к каждому Observe скриншот с метками элементов
+	Vision bool
+	// Observer — чем собирать элементы: ObserverJS (по умолчанию) или ObserverAX
+	Observer string
+	// TextBudget — сколько символов видимого текста страницы (заголовки, абзацы,
+	// ячейки) попадает в DOMSummary между элементами; 0 — только элементы
+	TextBudget int
+	// UploadDir — единственный каталог, из которого upload_file берет файлы;
+	// пусто — загрузка файлов выключена
+	UploadDir string
+
+	ids    *idRegistry         // Стабильные ID элементов (сбрасываются при Navigate)
+	frames []*frameScope       // Cross-origin фреймы последнего Observe
+	owners map[int]*frameScope // ID элемента → фрейм, в котором он живет
+
+	lastSnapshot *snapshot // Прошлое наблюдение для DOMChanges (сбрасывается при Navigate)
+
+	requests *requestTracker // Запросы текущей вкладки для ожидания network_idle
 }
 
 // NewBrowserService создает браузер.
@@ -62,6 +81,7 @@ func NewBrowserService(ctx context.Context, headless bool) (*BrowserService, err
 		browser:     browser,
 		CurrentPage: page,
 		ElementMap:  make(map[int]*rod.Element),
+		ids:         newIDRegistry(),
 	}, nil
 }
 
@@ -0,0 +1,126 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"browser-agent/internal/entity"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// ClickWith кликает выбранным способом. StrategyDefault — то же, что Click.
+func (s *BrowserService) ClickWith(ctx context.Context, id int, strategy entity.ActionStrategy) error {
+	switch strategy {
+	case entity.StrategyDefault, "":
+		return s.Click(ctx, id)
+
+	case entity.StrategyScrollIntoView:
+		el, err := s.GetElement(ctx, id)
+		if err != nil {
+			return fmt.Errorf("элемент ID %d не найден: %w", id, err)
+		}
+		scrollCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+		defer cancel()
+		// Прокрутка по центру: у края экрана элемент часто закрыт липкой шапкой
+		if _, err := el.Context(scrollCtx).Eval(`() => this.scrollIntoView({block: 'center', inline: 'center'})`); err != nil {
+			return fmt.Errorf("scroll into view: %w", err)
+		}
+		return s.mouseClick(ctx, id, proto.InputMouseButtonLeft, 1)
+
+	case entity.StrategyJSClick:
+		el, err := s.GetElement(ctx, id)
+		if err != nil {
+			return fmt.Errorf("элемент ID %d не найден: %w", id, err)
+		}
+		return s.watchTabs(ctx, func() error {
+			return s.dispatchJS(ctx, el, "click")
+		})
+	}
+	return fmt.Errorf("unsupported click strategy %q", strategy)
+}
+
+// TypeWith вводит текст выбранным способом. StrategyDefault — то же, что Type.
+func (s *BrowserService) TypeWith(ctx context.Context, id int, text string, strategy entity.ActionStrategy) error {
+	if strategy == entity.StrategyDefault || strategy == "" {
+		return s.Type(ctx, id, text)
+	}
+
+	el, err := s.GetElement(ctx, id)
+	if err != nil {
+		return fmt.Errorf("элемент ID %d не найден: %w", id, err)
+	}
+	typeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	switch strategy {
+	case entity.StrategyFocusType:
+		// Клик мышью мог попасть в оверлей — фокус из JS до него не доходит
+		if _, err := el.Context(typeCtx).Eval(FocusSelectScript); err != nil {
+			return fmt.Errorf("focus: %w", err)
+		}
+		if err := el.Page().Context(typeCtx).InsertText(text); err != nil {
+			return fmt.Errorf("ошибка ввода текста: %w", err)
+		}
+
+	case entity.StrategyJSValue:
+		if _, err := el.Context(typeCtx).Eval(SetTextValueScript, text); err != nil {
+			return fmt.Errorf("set value: %w", err)
+		}
+
+	default:
+		return fmt.Errorf("unsupported type strategy %q", strategy)
+	}
+
+	s.ElementMap = make(map[int]*rod.Element)
+	return nil
+}
+
+// Probe снимает состояние для проверки действия: URL, счетчик изменений DOM и,
+// если id > 0, значение и состояние элемента. Пропавший элемент — не ошибка
+// (Found = false): после клика он вполне мог исчезнуть.
+func (s *BrowserService) Probe(ctx context.Context, id int) (*entity.ActionProbe, error) {
+	probeCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	res, err := s.CurrentPage.Context(probeCtx).Eval(ProbeMutationsScript)
+	if err != nil {
+		return nil, fmt.Errorf("probe: %w", err)
+	}
+	probe := &entity.ActionProbe{Mutations: res.Value.Int()}
+	probe.URL, probe.TargetID = s.GetCurrentPageInfo()
+
+	if id <= 0 {
+		return probe, nil
+	}
+
+	// Без ожидания: GetElement ждал бы появления пропавшего элемента
+	el, ok := s.ElementMap[id]
+	if !ok {
+		page, _ := s.pageFor(id)
+		el, err = page.Context(probeCtx).Sleeper(rod.NotFoundSleeper).ElementByJS(rod.Eval(FindElementScript, id))
+		if err != nil {
+			return probe, nil
+		}
+	}
+
+	state, err := el.Context(probeCtx).Eval(ProbeElementScript)
+	if err != nil {
+		return probe, nil
+	}
+	var element struct {
+		Found    bool   `json:"found"`
+		Obscured bool   `json:"obscured"`
+		Value    string `json:"value"`
+		State    string `json:"state"`
+	}
+	if err := json.Unmarshal([]byte(state.Value.String()), &element); err != nil {
+		return nil, fmt.Errorf("json unmarshal: %w", err)
+	}
+	probe.Found, probe.Obscured = element.Found, element.Obscured
+	probe.Value, probe.State = element.Value, element.State
+	return probe, nil
+}
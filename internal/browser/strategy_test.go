@@ -0,0 +1,25 @@
+package browser
+
+import (
+	"testing"
+
+	"browser-agent/internal/entity"
+)
+
+func TestClickWith_JSClickFiresOnce(t *testing.T) {
+	s := newTestService(t)
+	base, _ := fixtureServer(t)
+	ctx, _ := openFixture(t, s, base+"/checkboxes.html")
+
+	// Двойной клик вернул бы нативный чекбокс в исходное состояние
+	if err := s.ClickWith(ctx, 4, entity.StrategyJSClick); err != nil {
+		t.Fatalf("ClickWith failed: %v", err)
+	}
+	res, err := s.CurrentPage.Eval(`() => document.getElementById('native').checked`)
+	if err != nil {
+		t.Fatalf("Eval: %v", err)
+	}
+	if !res.Value.Bool() {
+		t.Error("js_click must toggle the checkbox exactly once")
+	}
+}
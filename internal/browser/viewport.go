@@ -0,0 +1,109 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Положение элемента относительно вьюпорта (scannedElement.Viewport)
+const (
+	viewportIn    = "in"
+	viewportAbove = "above"
+	viewportBelow = "below"
+)
+
+// maxElements — сколько интерактивных элементов попадает в DOMSummary
+const maxElements = 600
+
+// offscreen — сколько интерактивных элементов выше и ниже экрана: всего и не
+// попавших в сводку из-за лимита
+type offscreen struct {
+	above, below             int
+	hiddenAbove, hiddenBelow int
+}
+
+// limitElements оставляет не больше limit интерактивных элементов: сначала всё,
+// что на экране, затем ближайшие к нему сверху и снизу по очереди. Порядок
+// документа сохраняется, блоки текста остаются все (их уже ограничил TextBudget).
+func limitElements(elements []scannedElement, limit int) ([]scannedElement, offscreen) {
+	var off offscreen
+	var above, in, below []int
+	for i, el := range elements {
+		if !el.Interactive {
+			continue
+		}
+		switch el.Viewport {
+		case viewportAbove:
+			above = append(above, i)
+			off.above++
+		case viewportBelow:
+			below = append(below, i)
+			off.below++
+		default:
+			in = append(in, i)
+		}
+	}
+
+	if len(above)+len(in)+len(below) <= limit {
+		return elements, off
+	}
+
+	keep := make(map[int]bool, limit)
+	for _, i := range in {
+		if len(keep) >= limit {
+			break
+		}
+		keep[i] = true
+	}
+	// Ближайшие к экрану: последние из тех, что выше, и первые из тех, что ниже
+	for a, b := len(above)-1, 0; len(keep) < limit && (a >= 0 || b < len(below)); {
+		if b < len(below) {
+			keep[below[b]] = true
+			b++
+		}
+		if a >= 0 && len(keep) < limit {
+			keep[above[a]] = true
+			a--
+		}
+	}
+
+	kept := make([]scannedElement, 0, limit)
+	for i, el := range elements {
+		if el.Interactive && !keep[i] {
+			if el.Viewport == viewportAbove {
+				off.hiddenAbove++
+			} else if el.Viewport == viewportBelow {
+				off.hiddenBelow++
+			}
+			continue
+		}
+		kept = append(kept, el)
+	}
+	return kept, off
+}
+
+// viewportSuffix — пометка для строки DOMSummary; элементы на экране без пометки
+func viewportSuffix(el scannedElement) string {
+	if el.Viewport == viewportAbove || el.Viewport == viewportBelow {
+		return " [" + el.Viewport + "]"
+	}
+	return ""
+}
+
+// offscreenLine — "--- 45 elements below the viewport (20 not listed) — scroll down ---"
+func offscreenLine(count, hidden int, where, scroll string) string {
+	if count == 0 {
+		return ""
+	}
+	noun := "elements"
+	if count == 1 {
+		noun = "element"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %d %s %s the viewport", count, noun, where)
+	if hidden > 0 {
+		fmt.Fprintf(&sb, " (%d not listed)", hidden)
+	}
+	fmt.Fprintf(&sb, " — scroll %s ---\n", scroll)
+	return sb.String()
+}
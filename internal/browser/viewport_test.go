@@ -0,0 +1,77 @@
+package browser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func at(viewport string, n int) []scannedElement {
+	out := make([]scannedElement, n)
+	for i := range out {
+		out[i] = scannedElement{Tag: "link", Interactive: true, Viewport: viewport}
+	}
+	return out
+}
+
+func TestLimitElements_PrefersViewport(t *testing.T) {
+	var elements []scannedElement
+	elements = append(elements, at(viewportAbove, 4)...)
+	elements = append(elements, scannedElement{Tag: "h1", Text: "Каталог", Viewport: viewportIn})
+	elements = append(elements, at(viewportIn, 3)...)
+	elements = append(elements, at(viewportBelow, 5)...)
+	for i := range elements {
+		elements[i].ID = i + 1
+	}
+
+	kept, off := limitElements(elements, 6)
+
+	// Все 3 на экране + по очереди ближайшие снизу и сверху; текст не считается
+	var ids []int
+	for _, el := range kept {
+		ids = append(ids, el.ID)
+	}
+	if want := []int{4, 5, 6, 7, 8, 9, 10}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Kept %v, want %v", ids, want)
+	}
+	if want := (offscreen{above: 4, below: 5, hiddenAbove: 3, hiddenBelow: 3}); off != want {
+		t.Errorf("Offscreen %+v, want %+v", off, want)
+	}
+
+	if got := offscreenLine(off.below, off.hiddenBelow, "below", "down"); got != "--- 5 elements below the viewport (3 not listed) — scroll down ---\n" {
+		t.Errorf("Unexpected line %q", got)
+	}
+
+	// В пределах лимита ничего не выкидывается
+	if kept, off := limitElements(elements, 100); len(kept) != len(elements) || off.hiddenAbove+off.hiddenBelow != 0 {
+		t.Errorf("Nothing must be dropped under the limit: %d kept, %+v", len(kept), off)
+	}
+}
+
+// Элементы за пределами экрана помечаются, а сводка сообщает, сколько их
+func TestObserve_Viewport(t *testing.T) {
+	s := newTestService(t)
+	base, _ := fixtureServer(t)
+	ctx, state := openFixture(t, s, base+"/viewport.html")
+	want := "[1] <button> [ACTION] Наверху\n" +
+		"[2] <button> [ACTION] Внизу [below]\n" +
+		"[3] <link> [NAVIGATE] Еще [below]\n" +
+		"--- 2 elements below the viewport — scroll down ---\n"
+	if state.DOMSummary != want {
+		t.Errorf("DOMSummary:\n%s--- want ---\n%s", state.DOMSummary, want)
+	}
+
+	// После прокрутки в самый низ верхняя кнопка оказывается над экраном,
+	// а разница наблюдений не считает прокрутку изменением элементов
+	s.CurrentPage.MustEval(`() => window.scrollTo(0, document.body.scrollHeight)`)
+	state, err := s.Observe(ctx)
+	if err != nil {
+		t.Fatalf("Observe failed: %v", err)
+	}
+	if !strings.HasPrefix(state.DOMSummary, "--- 1 element above the viewport — scroll up ---\n[1] <button> [ACTION] Наверху [above]\n") {
+		t.Errorf("Expected button above the viewport:\n%s", state.DOMSummary)
+	}
+	if state.Changes == nil || !state.Changes.Empty() {
+		t.Errorf("Scrolling must not produce changes: %+v", state.Changes)
+	}
+}
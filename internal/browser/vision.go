@@ -0,0 +1,48 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// screenshotQuality — качество JPEG: метки и текст читаются, а картинка
+// остается в пределах сотни-другой килобайт даже на 1920x1080
+const screenshotQuality = 70
+
+// MarkedScreenshot снимает видимую часть страницы, на которой каждый элемент
+// из последнего Observe обведен рамкой со своим ID (set-of-marks).
+// Метки рисуются только на время снимка.
+func (s *BrowserService) MarkedScreenshot(ctx context.Context) ([]byte, error) {
+	shotCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	page := s.CurrentPage.Context(shotCtx)
+
+	if _, err := page.Eval(DrawMarksScript); err != nil {
+		return nil, fmt.Errorf("draw marks: %w", err)
+	}
+	// Cross-origin фреймы рисуют метки своих элементов сами
+	for _, f := range s.frames {
+		_, _ = f.page.Context(shotCtx).Eval(DrawMarksScript)
+	}
+	// Снимаем метки даже если скриншот не удался
+	defer func() {
+		_, _ = s.CurrentPage.Context(ctx).Eval(ClearMarksScript)
+		for _, f := range s.frames {
+			_, _ = f.page.Context(ctx).Eval(ClearMarksScript)
+		}
+	}()
+
+	quality := screenshotQuality
+	shot, err := page.Screenshot(false, &proto.PageCaptureScreenshot{
+		Format:  proto.PageCaptureScreenshotFormatJpeg,
+		Quality: &quality,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("screenshot: %w", err)
+	}
+	return shot, nil
+}
@@ -0,0 +1,24 @@
+package browser
+
+import "testing"
+
+func TestObserve_VisionScreenshot(t *testing.T) {
+	s := newTestService(t)
+	s.Vision = true
+
+	base, _ := fixtureServer(t)
+	_, state := openFixture(t, s, base+"/forms.html")
+
+	if len(state.Screenshot) < 3 || state.Screenshot[0] != 0xFF || state.Screenshot[1] != 0xD8 {
+		t.Fatalf("Expected JPEG screenshot, got %d bytes", len(state.Screenshot))
+	}
+
+	// Метки живут только на время снимка
+	has, _, err := s.CurrentPage.Has("#agent-ids-overlay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if has {
+		t.Error("Overlay must be removed after screenshot")
+	}
+}
@@ -0,0 +1,250 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"browser-agent/internal/entity"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+const (
+	defaultWaitTimeout = 5 * time.Second
+	defaultQuiet       = 300 * time.Millisecond
+
+	// actionGrace — сколько после клика или нажатия ждать начала перехода или
+	// новой вкладки. Обычная ссылка начинает переход еще до возврата из Click,
+	// так что на странице без перехода ожидание почти всегда полное, но короткое.
+	actionGrace = 300 * time.Millisecond
+
+	// staleRequest — запрос дольше этого считается фоновым (long polling,
+	// стриминг) и тишине сети не мешает
+	staleRequest = 5 * time.Second
+)
+
+// observeWait — ожидание перед каждым Observe: догрузка и тишина DOM
+var observeWait = entity.WaitPolicy{
+	Conditions: []entity.WaitCondition{entity.WaitNavigation, entity.WaitDOMQuiet},
+	Quiet:      200 * time.Millisecond,
+	Timeout:    2 * time.Second,
+}
+
+// Wait ждет условий политики по очереди, пока не истечет общий Timeout.
+// Невыполненное условие — ошибка: после действий оркестратор только пишет ее
+// в лог, а wait_for возвращает модели.
+func (s *BrowserService) Wait(ctx context.Context, policy entity.WaitPolicy) error {
+	if policy.Timeout <= 0 {
+		policy.Timeout = defaultWaitTimeout
+	}
+	if policy.Quiet <= 0 {
+		policy.Quiet = defaultQuiet
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, policy.Timeout)
+	defer cancel()
+
+	for _, cond := range policy.Conditions {
+		var met bool
+		var err error
+		switch cond {
+		case entity.WaitNavigation:
+			met = s.waitLoad(waitCtx)
+
+		case entity.WaitNetworkIdle:
+			met = s.trackRequests().waitIdle(waitCtx, policy.Quiet)
+
+		case entity.WaitDOMQuiet:
+			met, err = s.waitScript(waitCtx, DOMQuietScript, func(left int64) []interface{} {
+				return []interface{}{policy.Quiet.Milliseconds(), left}
+			})
+
+		case entity.WaitText, entity.WaitTextGone:
+			if policy.Text == "" {
+				return fmt.Errorf("%s needs a text to wait for", cond)
+			}
+			gone := cond == entity.WaitTextGone
+			met, err = s.waitScript(waitCtx, WaitTextScript, func(left int64) []interface{} {
+				return []interface{}{policy.Text, gone, left}
+			})
+
+		default:
+			return fmt.Errorf("unknown wait condition %q", cond)
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", cond, err)
+		}
+		if !met {
+			return fmt.Errorf("%s not reached within %s", cond, policy.Timeout)
+		}
+	}
+	return nil
+}
+
+// waitLoad ждет события load текущей вкладки. Пока идет переход, старый документ
+// уже уничтожен, а новый еще не готов, — тогда Eval падает и повторяется.
+func (s *BrowserService) waitLoad(ctx context.Context) bool {
+	for {
+		if err := s.CurrentPage.Context(ctx).WaitLoad(); err == nil {
+			return true
+		}
+		sleepCtx(ctx, 50*time.Millisecond)
+		if ctx.Err() != nil {
+			return false
+		}
+	}
+}
+
+// waitScript выполняет JS-ожидание с оставшимся временем; если во время
+// ожидания страница перешла на другой адрес, ждет загрузки и проверяет заново
+func (s *BrowserService) waitScript(ctx context.Context, script string, args func(left int64) []interface{}) (bool, error) {
+	deadline, _ := ctx.Deadline()
+	for {
+		left := time.Until(deadline).Milliseconds()
+		if left <= 0 {
+			return false, nil
+		}
+		res, err := s.CurrentPage.Context(ctx).Eval(script, args(left)...)
+		if err == nil {
+			return res.Value.Bool(), nil
+		}
+		if ctx.Err() != nil {
+			return false, nil
+		}
+		if !s.waitLoad(ctx) {
+			return false, nil
+		}
+	}
+}
+
+// requestTracker следит за запросами вкладки постоянно, а не с начала ожидания:
+// запрос, который отправил клик, виден и тогда, когда Wait вызван после клика
+type requestTracker struct {
+	target proto.TargetTargetID
+	stop   func()
+
+	mu       sync.Mutex
+	inflight map[proto.NetworkRequestID]time.Time // Запрос → когда отправлен
+	last     time.Time                            // Последнее начало или конец запроса
+}
+
+// Картинки, шрифты, медиа и сокеты не мешают работать со страницей
+var ignoredRequests = map[proto.NetworkResourceType]bool{
+	proto.NetworkResourceTypeWebSocket:   true,
+	proto.NetworkResourceTypeEventSource: true,
+	proto.NetworkResourceTypeMedia:       true,
+	proto.NetworkResourceTypeImage:       true,
+	proto.NetworkResourceTypeFont:        true,
+	proto.NetworkResourceTypePing:        true,
+}
+
+// trackRequests возвращает трекер текущей вкладки, при смене вкладки — новый
+func (s *BrowserService) trackRequests() *requestTracker {
+	if s.requests != nil && s.requests.target == s.CurrentPage.TargetID {
+		return s.requests
+	}
+	if s.requests != nil {
+		s.requests.stop()
+	}
+
+	page, cancel := s.CurrentPage.WithCancel()
+	t := &requestTracker{
+		target:   page.TargetID,
+		stop:     cancel,
+		inflight: map[proto.NetworkRequestID]time.Time{},
+		last:     time.Now(),
+	}
+	wait := page.EachEvent(func(e *proto.NetworkRequestWillBeSent) {
+		if ignoredRequests[e.Type] {
+			return
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		// Редирект приходит тем же RequestID — время отправки не сдвигаем
+		if _, ok := t.inflight[e.RequestID]; !ok {
+			t.inflight[e.RequestID] = time.Now()
+		}
+		t.last = time.Now()
+	}, func(e *proto.NetworkLoadingFinished) {
+		t.done(e.RequestID)
+	}, func(e *proto.NetworkLoadingFailed) {
+		t.done(e.RequestID)
+	})
+	go wait()
+
+	s.requests = t
+	return t
+}
+
+func (t *requestTracker) done(id proto.NetworkRequestID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.inflight[id]; ok {
+		delete(t.inflight, id)
+		t.last = time.Now()
+	}
+}
+
+// idle — нет активных запросов quiet подряд
+func (t *requestTracker) idle(quiet time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, sent := range t.inflight {
+		if time.Since(sent) < staleRequest {
+			return false
+		}
+	}
+	return time.Since(t.last) >= quiet
+}
+
+// waitIdle проверяет состояние, которое обновляют события сети; сам опрос
+// ничего не запрашивает у браузера
+func (t *requestTracker) waitIdle(ctx context.Context, quiet time.Duration) bool {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for !t.idle(quiet) {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
+
+// navigationWatch — подписка, сделанная до действия: вкладка, которую оно
+// открыло, или переход текущей вкладки
+type navigationWatch struct {
+	tabs    chan proto.TargetTargetID
+	started chan struct{}
+	stop    func()
+}
+
+func (s *BrowserService) watchNavigation(ctx context.Context) *navigationWatch {
+	watchCtx, cancel := context.WithCancel(ctx)
+	w := &navigationWatch{tabs: make(chan proto.TargetTargetID, 1), started: make(chan struct{}, 1), stop: cancel}
+
+	waitTab := s.browser.Context(watchCtx).EachEvent(func(e *proto.TargetTargetCreated) bool {
+		if e.TargetInfo.Type != proto.TargetTargetInfoTypePage {
+			return false
+		}
+		w.tabs <- e.TargetInfo.TargetID
+		return true
+	})
+	mainFrame := s.CurrentPage.FrameID
+	waitNav := s.CurrentPage.Context(watchCtx).EachEvent(func(e *proto.PageFrameStartedLoading) bool {
+		if e.FrameID != mainFrame {
+			return false
+		}
+		w.started <- struct{}{}
+		return true
+	})
+	go waitTab()
+	go waitNav()
+	return w
+}
@@ -0,0 +1,63 @@
+package browser
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"browser-agent/internal/entity"
+)
+
+func TestWait_NetworkDOMAndText(t *testing.T) {
+	s := newTestService(t)
+
+	base, mux := fixtureServer(t)
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(700 * time.Millisecond)
+		_, _ = w.Write([]byte("Заказ №42"))
+	})
+	ctx, _ := openFixture(t, s, base+"/wait.html")
+
+	// Click не ждет ответа API: без перехода он возвращается сразу после actionGrace
+	start := time.Now()
+	if err := s.Click(ctx, 1); err != nil {
+		t.Fatalf("Click failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 600*time.Millisecond {
+		t.Errorf("Click took %v", elapsed)
+	}
+
+	// Запрос отправлен кликом до начала ожидания — трекер все равно его видит
+	settle := entity.WaitPolicy{
+		Conditions: []entity.WaitCondition{entity.WaitNavigation, entity.WaitNetworkIdle, entity.WaitDOMQuiet},
+		Quiet:      200 * time.Millisecond,
+		Timeout:    5 * time.Second,
+	}
+	if err := s.Wait(ctx, settle); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 700*time.Millisecond {
+		t.Errorf("network_idle returned before the request finished (%v)", elapsed)
+	}
+
+	text := entity.WaitPolicy{Conditions: []entity.WaitCondition{entity.WaitText}, Text: "заказ №42", Timeout: time.Second}
+	if err := s.Wait(ctx, text); err != nil {
+		t.Errorf("Wait text: %v", err)
+	}
+	gone := entity.WaitPolicy{Conditions: []entity.WaitCondition{entity.WaitTextGone}, Text: "Загрузка", Timeout: time.Second}
+	if err := s.Wait(ctx, gone); err != nil {
+		t.Errorf("Wait text_gone: %v", err)
+	}
+
+	// Повторное ожидание тишины на спокойной странице не ждет заново
+	start = time.Now()
+	if err := s.Wait(ctx, settle); err != nil || time.Since(start) > 150*time.Millisecond {
+		t.Errorf("Second settle: %v after %v", err, time.Since(start))
+	}
+
+	missing := entity.WaitPolicy{Conditions: []entity.WaitCondition{entity.WaitText}, Text: "Отменен", Timeout: 500 * time.Millisecond}
+	if err := s.Wait(ctx, missing); err == nil || !strings.Contains(err.Error(), "text not reached within 500ms") {
+		t.Errorf("Expected timeout, got %v", err)
+	}
+}
@@ -3,15 +3,44 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
 
 // Config holds the application configuration
 type Config struct {
-	APIKey string
-	Model  string
-	Url    string
+	// Provider — какой API использовать: openai (Chat Completions),
+	// openai-responses, anthropic или ollama
+	Provider string
+	APIKey   string
+	Model    string
+	Url      string
+
+	// MemoryPersist — хранить факты memorize между задачами сессии
+	MemoryPersist bool
+
+	// TokenBudget — лимит промпта в токенах; при превышении старая история
+	// сжимается, а DOM обрезается. 0 — без ограничения.
+	TokenBudget int
+
+	// Vision — прикладывать к наблюдению скриншот с метками элементов
+	// (нужна vision-модель)
+	Vision bool
+
+	// Observer — источник элементов страницы: js (сканер-скрипт) или ax (дерево доступности)
+	Observer string
+
+	// TextBudget — лимит символов текста страницы в DOM-сводке; 0 — только элементы
+	TextBudget int
+
+	// UploadDir — каталог с файлами, которые агенту разрешено прикреплять к формам
+	UploadDir string
+
+	// VerifyActions — проверять, что click и type сработали, и повторять их
+	// запасными способами (ActionRetries — сколько запасных способов пробовать)
+	VerifyActions bool
+	ActionRetries int
 }
 
 // LoadConfig loads configuration from .env file and environment variables
@@ -22,23 +51,68 @@ func LoadConfig() (*Config, error) {
 		fmt.Printf("Warning: could not load .env file: %v\n", err)
 	}
 
+	provider := getEnvOrDefault("LLM_PROVIDER", "openai")
+
+	// Дефолтный URL есть только у OpenAI-совместимого режима (Groq),
+	// остальные адаптеры подставляют адрес своего API сами
+	defaultURL := ""
+	if provider == "openai" {
+		defaultURL = "https://api.groq.com/openai/v1"
+	}
+
 	config := &Config{
-		APIKey: getEnvOrDefault("API_KEY", ""),
-		Model:  getEnvOrDefault("MODEL", "gpt-3.5-turbo"),
-		Url:    getEnvOrDefault("URL", "https://api.groq.com/openai/v1"),
+		Provider: provider,
+		APIKey:   getEnvOrDefault("API_KEY", ""),
+		Model:    getEnvOrDefault("MODEL", "gpt-3.5-turbo"),
+		Url:      getEnvOrDefault("URL", defaultURL),
+
+		MemoryPersist: getEnvOrDefault("MEMORY_PERSIST", "false") == "true",
+		Vision:        getEnvOrDefault("VISION", "false") == "true",
+		Observer:      getEnvOrDefault("OBSERVER", "js"),
+		UploadDir:     getEnvOrDefault("UPLOAD_DIR", ""),
+		VerifyActions: getEnvOrDefault("VERIFY_ACTIONS", "true") == "true",
+	}
+
+	if config.Observer != "js" && config.Observer != "ax" {
+		return nil, fmt.Errorf("OBSERVER must be js or ax, got %q", config.Observer)
+	}
+
+	budget, err := strconv.Atoi(getEnvOrDefault("TOKEN_BUDGET", "30000"))
+	if err != nil || budget < 0 {
+		return nil, fmt.Errorf("TOKEN_BUDGET must be a non-negative integer, got %q", os.Getenv("TOKEN_BUDGET"))
+	}
+	config.TokenBudget = budget
+
+	textBudget, err := strconv.Atoi(getEnvOrDefault("TEXT_BUDGET", "3000"))
+	if err != nil || textBudget < 0 {
+		return nil, fmt.Errorf("TEXT_BUDGET must be a non-negative integer, got %q", os.Getenv("TEXT_BUDGET"))
+	}
+	config.TextBudget = textBudget
+
+	retries, err := strconv.Atoi(getEnvOrDefault("ACTION_RETRIES", "2"))
+	if err != nil || retries < 0 {
+		return nil, fmt.Errorf("ACTION_RETRIES must be a non-negative integer, got %q", os.Getenv("ACTION_RETRIES"))
+	}
+	config.ActionRetries = retries
+
+	if config.UploadDir != "" {
+		if info, err := os.Stat(config.UploadDir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("UPLOAD_DIR must be an existing directory, got %q", config.UploadDir)
+		}
 	}
 
-	// Validate required fields
-	if config.APIKey == "" {
+	// Validate required fields (локальной Ollama ключ не нужен)
+	if config.APIKey == "" && config.Provider != "ollama" {
 		return nil, fmt.Errorf("API_KEY is required but not set in environment or .env file")
 	}
 
 	return config, nil
 }
 
-// getEnvOrDefault retrieves an environment variable or returns a default value
+// getEnvOrDefault retrieves an environment variable or returns a default value.
+// Пустое значение (URL= из .env.example) считается незаданным.
 func getEnvOrDefault(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
+	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
@@ -0,0 +1,26 @@
+package entity
+
+// ActionProbe — снимок страницы и элемента для проверки действия: оркестратор
+// сравнивает снимки до и после click и читает значение поля после type
+type ActionProbe struct {
+	URL       string
+	TargetID  string // Вкладка: клик мог открыть новую
+	Mutations int    // Счетчик изменений DOM страницы (сбрасывается с перезагрузкой)
+
+	Found    bool   // Элемент еще на странице
+	Obscured bool   // Центр элемента на экране закрыт другим элементом (оверлей); за экраном — false
+	Value    string // value поля или текст contenteditable
+	State    string // checked, aria-*, open, disabled, class — одной строкой для сравнения
+}
+
+// ActionStrategy — способ выполнить click или type. Если действие не дало
+// результата, оркестратор пробует следующий способ.
+type ActionStrategy string
+
+const (
+	StrategyDefault        ActionStrategy = "default"          // Мышь / ввод с клавиатуры
+	StrategyScrollIntoView ActionStrategy = "scroll_into_view" // Прокрутить к элементу и кликнуть мышью
+	StrategyJSClick        ActionStrategy = "js_click"         // Событие click из JS (элемент перекрыт оверлеем)
+	StrategyFocusType      ActionStrategy = "focus_type"       // Фокус из JS, затем вставка текста
+	StrategyJSValue        ActionStrategy = "js_value"         // value через нативный сеттер + input/change
+)
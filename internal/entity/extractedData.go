@@ -0,0 +1,11 @@
+package entity
+
+// ExtractedData — таблица или повторяющийся список со страницы в виде строк
+// (инструменты extract_table / extract_list)
+type ExtractedData struct {
+	Kind    string              `json:"kind"` // table | list
+	URL     string              `json:"url,omitempty"`
+	Columns []string            `json:"columns"` // Порядок колонок: ключи в Rows его не сохраняют
+	Rows    []map[string]string `json:"rows"`
+	Total   int                 `json:"total"` // Строк на странице (Rows может быть обрезан max_rows)
+}
@@ -4,4 +4,26 @@ type BrowserState struct {
 	URL        string
 	Title      string
 	DOMSummary string
+
+	// Screenshot — JPEG вьюпорта с рамками и номерами элементов (режим vision).
+	// Пустой, если vision выключен или снимок не удался.
+	Screenshot []byte
+
+	// Changes — разница с прошлым наблюдением той же страницы.
+	// nil — сравнивать не с чем (первый шаг или только что был navigate).
+	Changes *DOMChanges
+}
+
+// DOMChanges — чем текущее наблюдение отличается от предыдущего (по стабильным ID).
+// Строки в формате DOMSummary: "[5] <checkbox> [SELECT] Спам (V)".
+type DOMChanges struct {
+	PrevURL string   // Прежний URL, если он сменился
+	Added   []string // Появившиеся элементы
+	Removed []string // Исчезнувшие элементы
+	Changed []string // "[5] <checkbox> [SELECT] Спам ( ) -> [SELECT] Спам (V)"
+}
+
+// Empty — ничего не изменилось (действие могло не сработать)
+func (c *DOMChanges) Empty() bool {
+	return c.PrevURL == "" && len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0
 }
@@ -0,0 +1,33 @@
+package entity
+
+import "encoding/json"
+
+// TaskStatus — итоговый статус выполнения задачи
+type TaskStatus string
+
+const (
+	TaskQueued  TaskStatus = "queued"  // Ждет своей очереди к браузеру (API-сервер)
+	TaskRunning TaskStatus = "running" // Выполняется прямо сейчас
+
+	TaskCompleted     TaskStatus = "completed"      // Агент вызвал submit_task_result
+	TaskStepLimit     TaskStatus = "step_limit"     // Исчерпан лимит шагов
+	TaskObserveFailed TaskStatus = "observe_failed" // Браузер не смог снять состояние страницы
+	TaskCancelled     TaskStatus = "cancelled"      // Контекст отменен (Ctrl+C, дедлайн, API)
+)
+
+// TaskResult — машиночитаемый итог выполнения одной задачи
+// (используется в batch-режиме, поэтому с JSON-тегами)
+type TaskResult struct {
+	Task        string          `json:"task"`
+	Status      TaskStatus      `json:"status"`
+	FinalReport string          `json:"final_report,omitempty"` // Аргумент final_report из submit_task_result
+	Output      json.RawMessage `json:"output,omitempty"`       // Аргумент result, проверенный по JSON Schema задачи
+	Steps       int             `json:"steps"`
+	FinalURL    string          `json:"final_url,omitempty"`
+	Error       string          `json:"error,omitempty"`
+
+	History []ActionRecord `json:"history"`          // Полная история действий за задачу
+	Memory  []string       `json:"memory,omitempty"` // Факты, сохраненные через memorize
+
+	Extracted []ExtractedData `json:"extracted,omitempty"` // Таблицы и списки из extract_table / extract_list
+}
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// WaitCondition — событие, которого ждет браузер после действия или по wait_for
+type WaitCondition string
+
+const (
+	WaitNavigation  WaitCondition = "navigation"   // Начатый переход завершен: документ загружен
+	WaitNetworkIdle WaitCondition = "network_idle" // Нет запросов (документы, XHR, fetch) в течение Quiet
+	WaitDOMQuiet    WaitCondition = "dom_quiet"    // DOM не меняется в течение Quiet
+	WaitText        WaitCondition = "text"         // На странице появился текст Text
+	WaitTextGone    WaitCondition = "text_gone"    // Текст Text пропал (спиннер, "Загрузка...")
+)
+
+// WaitPolicy — чего ждать. Условия проверяются по очереди с общим Timeout:
+// на быстрой странице все выполняются сразу, на медленной ожидание обрывается
+// по Timeout, а не тянется фиксированную паузу.
+type WaitPolicy struct {
+	Conditions []WaitCondition
+	Text       string        // Для WaitText и WaitTextGone
+	Quiet      time.Duration // Сколько тишины считать покоем (сеть, DOM)
+	Timeout    time.Duration
+}
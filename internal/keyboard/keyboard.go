@@ -0,0 +1,240 @@
+// Package keyboard — единая модель клавиш для инструмента press: названия, которые
+// модель видит в описании инструмента, и разбор того, что она присылает, в клавиши
+// CDP. Поддерживаются именованные клавиши (Enter, ArrowDown, F5), одиночные
+// символы, аккорды с модификаторами (Control+A, Shift+Tab, Meta+Enter),
+// повтор (ArrowDown*3) и последовательности через пробел (Tab Tab Enter).
+package keyboard
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-rod/rod/lib/input"
+)
+
+// MaxPresses — сколько нажатий допускает одна последовательность с учетом повторов
+const MaxPresses = 30
+
+// Chord — одно нажатие: модификаторы зажимаются, Key нажимается и отпускается
+type Chord struct {
+	Modifiers []input.Key
+	Key       input.Key
+	Name      string // Каноническая запись: "Control+Shift+Tab"
+}
+
+// Sequence — нажатия по порядку
+type Sequence []Chord
+
+// String — каноническая запись последовательности через пробел
+func (seq Sequence) String() string {
+	names := make([]string, len(seq))
+	for i, c := range seq {
+		names[i] = c.Name
+	}
+	return strings.Join(names, " ")
+}
+
+type namedKey struct {
+	name string
+	key  input.Key
+}
+
+// modifiers — в порядке канонической записи аккорда
+var modifiers = []namedKey{
+	{"Control", input.ControlLeft},
+	{"Alt", input.AltLeft},
+	{"Shift", input.ShiftLeft},
+	{"Meta", input.MetaLeft},
+}
+
+// named — клавиши без символа; порядок — порядок в описании инструмента
+var named = []namedKey{
+	{"Enter", input.Enter},
+	{"Tab", input.Tab},
+	{"Escape", input.Escape},
+	{"Backspace", input.Backspace},
+	{"Delete", input.Delete},
+	{"Space", input.Space},
+	{"ArrowUp", input.ArrowUp},
+	{"ArrowDown", input.ArrowDown},
+	{"ArrowLeft", input.ArrowLeft},
+	{"ArrowRight", input.ArrowRight},
+	{"Home", input.Home},
+	{"End", input.End},
+	{"PageUp", input.PageUp},
+	{"PageDown", input.PageDown},
+	{"Insert", input.Insert},
+	{"F1", input.F1}, {"F2", input.F2}, {"F3", input.F3}, {"F4", input.F4},
+	{"F5", input.F5}, {"F6", input.F6}, {"F7", input.F7}, {"F8", input.F8},
+	{"F9", input.F9}, {"F10", input.F10}, {"F11", input.F11}, {"F12", input.F12},
+}
+
+// aliases — другие написания (после normalize), которые модели шлют чаще всего
+var aliases = map[string]string{
+	"esc": "Escape", "return": "Enter", "del": "Delete", "ins": "Insert",
+	"up": "ArrowUp", "down": "ArrowDown", "left": "ArrowLeft", "right": "ArrowRight",
+	"pgup": "PageUp", "pgdn": "PageDown", "pagedn": "PageDown", "spacebar": "Space",
+	"ctrl": "Control", "cmd": "Meta", "command": "Meta", "win": "Meta", "super": "Meta",
+	"option": "Alt", "opt": "Alt",
+}
+
+// characters — одиночные символы, для которых есть физическая клавиша
+const characters = "abcdefghijklmnopqrstuvwxyz0123456789`-=[]\\;',./"
+
+var (
+	byName     = map[string]namedKey{}
+	isModifier = map[input.Key]bool{}
+)
+
+func init() {
+	for _, k := range append(append([]namedKey{}, named...), modifiers...) {
+		byName[normalize(k.name)] = k
+	}
+	for _, m := range modifiers {
+		isModifier[m.key] = true
+	}
+	for alias, name := range aliases {
+		byName[alias] = byName[normalize(name)]
+	}
+}
+
+// Names — канонические имена именованных клавиш для описания инструмента
+func Names() []string {
+	out := make([]string, len(named))
+	for i, k := range named {
+		out[i] = k.name
+	}
+	return out
+}
+
+// ModifierNames — канонические имена модификаторов
+func ModifierNames() []string {
+	out := make([]string, len(modifiers))
+	for i, m := range modifiers {
+		out[i] = m.name
+	}
+	return out
+}
+
+// Examples — записи из описания инструмента; тесты проверяют, что все они разбираются
+var Examples = []string{"Enter", "Control+A", "Shift+Tab", "Meta+Enter", "ArrowDown*3", "Tab Tab Enter"}
+
+// Parse разбирает запись вида "Control+A Backspace" или "ArrowDown*3".
+// Регистр и разделители в именах не важны: "enter", "arrow_down", "Ctrl+a" тоже подходят.
+func Parse(s string) (Sequence, error) {
+	tokens := strings.Fields(s)
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty key")
+	}
+
+	var seq Sequence
+	for _, tok := range tokens {
+		chord, repeat, err := parseToken(tok)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < repeat; i++ {
+			seq = append(seq, chord)
+		}
+		if len(seq) > MaxPresses {
+			return nil, fmt.Errorf("too many key presses in %q (max %d)", s, MaxPresses)
+		}
+	}
+	return seq, nil
+}
+
+// parseToken — один аккорд с необязательным повтором "*N"
+func parseToken(tok string) (Chord, int, error) {
+	repeat := 1
+	if i := strings.LastIndex(tok, "*"); i > 0 && i < len(tok)-1 {
+		n, err := strconv.Atoi(tok[i+1:])
+		if err != nil || n < 1 {
+			return Chord{}, 0, fmt.Errorf("invalid repeat in %q", tok)
+		}
+		tok, repeat = tok[:i], n
+	}
+
+	// "+" сам по себе тоже клавиша: "Control++" — это Control и "+"
+	parts := strings.Split(tok, "+")
+	if tok == "+" || strings.HasSuffix(tok, "++") {
+		parts = append(parts[:len(parts)-2], "+")
+	}
+
+	var chord Chord
+	var names []string
+	for i, part := range parts {
+		k, err := lookup(part)
+		if err != nil {
+			return Chord{}, 0, fmt.Errorf("%w in %q (named keys: %s; modifiers: %s)",
+				err, tok, strings.Join(Names(), ", "), strings.Join(ModifierNames(), ", "))
+		}
+		if i == len(parts)-1 {
+			chord.Key = k.key
+			break
+		}
+		if !isModifier[k.key] {
+			return Chord{}, 0, fmt.Errorf("%q is not a modifier in %q: only the last key of a chord can be a regular key", k.name, tok)
+		}
+		if !containsKey(chord.Modifiers, k.key) {
+			chord.Modifiers = append(chord.Modifiers, k.key)
+		}
+	}
+
+	// Каноническая запись: модификаторы в фиксированном порядке
+	var ordered []input.Key
+	for _, m := range modifiers {
+		if containsKey(chord.Modifiers, m.key) {
+			ordered = append(ordered, m.key)
+			names = append(names, m.name)
+		}
+	}
+	chord.Modifiers = ordered
+	chord.Name = strings.Join(append(names, keyName(chord.Key)), "+")
+	return chord, repeat, nil
+}
+
+func lookup(part string) (namedKey, error) {
+	if part == "" {
+		return namedKey{}, fmt.Errorf("empty key")
+	}
+	if k, ok := byName[normalize(part)]; ok {
+		return k, nil
+	}
+	// Одиночный символ: "a", "A", "/", "+"
+	if len(part) == 1 {
+		c := strings.ToLower(part)
+		if strings.Contains(characters, c) {
+			return namedKey{strings.ToUpper(c), input.Key(c[0])}, nil
+		}
+		if c == "+" {
+			return namedKey{"+", input.NumpadAdd}, nil
+		}
+	}
+	return namedKey{}, fmt.Errorf("unknown key %q", part)
+}
+
+// keyName — каноническое имя клавиши для Chord.Name
+func keyName(k input.Key) string {
+	for _, n := range append(append([]namedKey{}, named...), modifiers...) {
+		if n.key == k {
+			return n.name
+		}
+	}
+	return strings.ToUpper(string(rune(k)))
+}
+
+// normalize — "Arrow_Down", "arrow-down", "ArrowDown" → "arrowdown"
+func normalize(s string) string {
+	s = strings.ToLower(s)
+	return strings.NewReplacer("_", "", "-", "", " ", "").Replace(s)
+}
+
+func containsKey(keys []input.Key, k input.Key) bool {
+	for _, x := range keys {
+		if x == k {
+			return true
+		}
+	}
+	return false
+}
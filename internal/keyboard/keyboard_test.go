@@ -0,0 +1,101 @@
+package keyboard
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-rod/rod/lib/input"
+)
+
+// Всё, что модель видит в описании инструмента press, должно разбираться
+// и превращаться в клавиши, известные rod (Info паникует на неизвестной)
+func TestParse_AdvertisedKeys(t *testing.T) {
+	var all []string
+	all = append(all, Names()...)
+	all = append(all, ModifierNames()...)
+	all = append(all, Examples...)
+	for _, m := range ModifierNames() {
+		for _, n := range Names() {
+			all = append(all, m+"+"+n)
+		}
+	}
+	for _, c := range characters {
+		all = append(all, "Control+"+string(c))
+	}
+
+	for _, s := range all {
+		seq, err := Parse(s)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", s, err)
+			continue
+		}
+		for _, chord := range seq {
+			for _, k := range append(chord.Modifiers, chord.Key) {
+				func() {
+					defer func() {
+						if r := recover(); r != nil {
+							t.Errorf("Parse(%q): key %d is unknown to rod", s, k)
+						}
+					}()
+					_ = k.Info()
+				}()
+			}
+		}
+	}
+}
+
+func TestParse_Normalization(t *testing.T) {
+	cases := map[string]string{
+		// Старые имена PressKey и старый enum инструмента
+		"enter":      "Enter",
+		"arrow_down": "ArrowDown",
+		"ArrowUp":    "ArrowUp",
+		"Delete":     "Delete",
+		"escape":     "Escape",
+		"space":      "Space",
+		// Синонимы и регистр
+		"esc":            "Escape",
+		"Return":         "Enter",
+		"PGDN":           "PageDown",
+		"ctrl+a":         "Control+A",
+		"Cmd+Enter":      "Meta+Enter",
+		"shift+ctrl+tab": "Control+Shift+Tab",
+		"Ctrl+Ctrl+A":    "Control+A",
+		"Control++":      "Control++",
+		"Shift":          "Shift",
+		"Tab Tab  Enter": "Tab Tab Enter",
+		"ArrowDown*3":    "ArrowDown ArrowDown ArrowDown",
+		"Control+A Del":  "Control+A Delete",
+		"alt+f4":         "Alt+F4",
+		"/":              "/",
+	}
+	for in, want := range cases {
+		seq, err := Parse(in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", in, err)
+			continue
+		}
+		if got := seq.String(); got != want {
+			t.Errorf("Parse(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	seq, _ := Parse("Shift+Ctrl+a")
+	if len(seq) != 1 || seq[0].Key != input.KeyA || len(seq[0].Modifiers) != 2 || seq[0].Modifiers[0] != input.ControlLeft {
+		t.Errorf("Unexpected chord: %+v", seq)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, in := range []string{"", "   ", "Hyper", "A+B", "Control+", "Enter*0", "Tab*31", "ё"} {
+		if seq, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) = %q, want an error", in, seq)
+		}
+	}
+
+	// Ошибка подсказывает модели допустимые имена
+	_, err := Parse("Hyper")
+	if err == nil || !strings.Contains(err.Error(), "ArrowDown") {
+		t.Errorf("Error must list named keys, got %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"browser-agent/internal/entity"
+)
+
+const (
+	// recentHistorySteps — сколько последних шагов истории всегда идут как есть
+	recentHistorySteps = 5
+	// compactResultRunes — до скольких символов режется result в сжатой истории
+	compactResultRunes = 120
+	// minDOMTokens — DOM не режется ниже этого порога, даже если бюджет уже съеден
+	minDOMTokens = 500
+	// screenshotTokens — примерная цена одного скриншота у vision-моделей
+	screenshotTokens = 1100
+)
+
+// EstimateTokens — грубая оценка числа токенов без токенизатора модели.
+// Латиница и разметка дают ~4 символа на токен, кириллица и прочий
+// не-ASCII — ~2 символа на токен. Для бюджета этого достаточно.
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + (other+1)/2
+}
+
+// PromptUsage — сколько токенов (по EstimateTokens) занял каждый раздел промпта
+type PromptUsage struct {
+	System     int `json:"system"`
+	Task       int `json:"task"`
+	Memory     int `json:"memory"`
+	Changes    int `json:"changes,omitempty"`
+	History    int `json:"history"`
+	DOM        int `json:"dom"`
+	Screenshot int `json:"screenshot,omitempty"`
+	Total      int `json:"total"`
+	Budget     int `json:"budget"` // 0 — без ограничения
+
+	CompactedSteps  int `json:"compacted_steps,omitempty"`  // Шаги истории, сжатые или свернутые
+	TrimmedElements int `json:"trimmed_elements,omitempty"` // Строки DOM, не влезшие в бюджет
+}
+
+func (u PromptUsage) String() string {
+	s := fmt.Sprintf("system=%d task=%d memory=%d changes=%d history=%d dom=%d",
+		u.System, u.Task, u.Memory, u.Changes, u.History, u.DOM)
+	if u.Screenshot > 0 {
+		s += fmt.Sprintf(" screenshot=%d", u.Screenshot)
+	}
+	s += fmt.Sprintf(" total=%d", u.Total)
+	if u.Budget > 0 {
+		s += fmt.Sprintf("/%d", u.Budget)
+	}
+	if u.CompactedSteps > 0 {
+		s += fmt.Sprintf(", сжато шагов: %d", u.CompactedSteps)
+	}
+	if u.TrimmedElements > 0 {
+		s += fmt.Sprintf(", обрезано элементов DOM: %d", u.TrimmedElements)
+	}
+	return s
+}
+
+// historyLevel — степень сжатия истории
+type historyLevel int
+
+const (
+	historyFull      historyLevel = iota // Все шаги как есть
+	historyCompact                       // Старые шаги без мыслей и с коротким result
+	historyCollapsed                     // Старые шаги свернуты в одну строку-сводку
+)
+
+const historyHeader = "PREVIOUS ACTIONS LOG (Read-Only Context):\n"
+
+// renderHistory собирает JSONL-лог действий. Последние recentHistorySteps шагов
+// и все шаги с ошибкой идут целиком на любом уровне — модель должна помнить,
+// что уже не сработало. Возвращает текст и число сжатых шагов.
+func renderHistory(history []entity.ActionRecord, level historyLevel) (string, int) {
+	if len(history) == 0 {
+		return "", 0
+	}
+
+	recentFrom := len(history) - recentHistorySteps
+	if level == historyFull {
+		recentFrom = 0
+	}
+
+	var sb strings.Builder
+	sb.WriteString(historyHeader)
+	if recentFrom > 0 {
+		sb.WriteString("(older steps are compacted to save context; errors are kept in full)\n")
+	}
+
+	compacted := 0
+	collapsedFrom := 0
+	collapsedCounts := map[string]int{}
+
+	// flushCollapsed пишет накопленную сводку перед очередной полной записью,
+	// чтобы порядок шагов в логе не ломался
+	flushCollapsed := func(to int) {
+		if len(collapsedCounts) == 0 {
+			return
+		}
+		writeJSONLine(&sb, map[string]interface{}{
+			"steps":   fmt.Sprintf("%d-%d", collapsedFrom+1, to),
+			"summary": summarizeActions(collapsedCounts),
+		})
+		collapsedCounts = map[string]int{}
+	}
+
+	for i, record := range history {
+		if i >= recentFrom || isErrorResult(record.Result) {
+			flushCollapsed(i)
+			writeJSONLine(&sb, map[string]interface{}{
+				"step":    i + 1,
+				"thought": record.Reasoning,
+				"action":  record.Action,
+				"args":    record.Args,
+				"result":  record.Result,
+			})
+			continue
+		}
+
+		compacted++
+		if level == historyCompact {
+			writeJSONLine(&sb, map[string]interface{}{
+				"step":   i + 1,
+				"action": record.Action,
+				"args":   record.Args,
+				"result": truncateRunes(record.Result, compactResultRunes),
+			})
+			continue
+		}
+
+		if len(collapsedCounts) == 0 {
+			collapsedFrom = i
+		}
+		collapsedCounts[record.Action]++
+	}
+	flushCollapsed(len(history))
+
+	return sb.String(), compacted
+}
+
+// trimDOM отрезает DOM по целым строкам (элементам), чтобы он влез в maxTokens.
+// Первыми уходят строки дальше всего от экрана: самые верхние [above] и самые
+// нижние [below], по очереди, как в limitElements сканера. Строки на экране
+// режутся только после всех остальных, и тогда — с конца. Строки "--- N elements
+// above/below the viewport ---" остаются всегда.
+func trimDOM(dom string, maxTokens int) (string, int) {
+	if EstimateTokens(dom) <= maxTokens {
+		return dom, 0
+	}
+
+	lines := strings.SplitAfter(dom, "\n")
+	keep := make([]bool, len(lines))
+	used := 0
+	var above, in, below []int
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "---"):
+			keep[i] = true
+			used += EstimateTokens(line)
+		case strings.HasSuffix(trimmed, " [above]"):
+			above = append(above, i)
+		case strings.HasSuffix(trimmed, " [below]"):
+			below = append(below, i)
+		default:
+			in = append(in, i)
+		}
+	}
+
+	// Порядок важности: экран сверху вниз, затем ближайшие к нему снизу и сверху по очереди
+	order := append([]int(nil), in...)
+	for a, b := len(above)-1, 0; a >= 0 || b < len(below); {
+		if b < len(below) {
+			order = append(order, below[b])
+			b++
+		}
+		if a >= 0 {
+			order = append(order, above[a])
+			a--
+		}
+	}
+	for _, i := range order {
+		t := EstimateTokens(lines[i])
+		if used+t > maxTokens {
+			break
+		}
+		keep[i] = true
+		used += t
+	}
+
+	var sb strings.Builder
+	trimmed := 0
+	for i, line := range lines {
+		if keep[i] {
+			sb.WriteString(line)
+		} else if strings.TrimSpace(line) != "" {
+			trimmed++
+		}
+	}
+	fmt.Fprintf(&sb, "\n... (%d more elements trimmed to fit token budget, scroll or use read_text) ...\n", trimmed)
+	return sb.String(), trimmed
+}
+
+func isErrorResult(result string) bool {
+	return strings.HasPrefix(result, "Error")
+}
+
+func writeJSONLine(sb *strings.Builder, entry map[string]interface{}) {
+	jsonBytes, _ := json.Marshal(entry)
+	sb.WriteString(string(jsonBytes) + "\n")
+}
+
+// summarizeActions — "click x3, type x2" (по алфавиту, чтобы промпт был стабильным)
+func summarizeActions(counts map[string]int) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s x%d", name, counts[name]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
@@ -6,33 +6,35 @@ import (
 	"fmt"
 
 	"browser-agent/internal/entity"
-
-	"github.com/openai/openai-go/v3"
-	"github.com/openai/openai-go/v3/option"
 )
 
-// Client реализует интерфейс Brain
+// Client реализует интерфейс Brain поверх любого Provider
 type Client struct {
-	client *openai.Client
-	model  string
+	provider Provider
+	model    string
 
 	Task          string
+	Memory        []string // Факты из памяти агента (отдельная секция промпта)
 	ActionHistory []entity.ActionRecord
+
+	// Schema — JSON Schema результата текущей задачи (nil — только текстовый отчет)
+	Schema map[string]any
+
+	// TokenBudget — лимит промпта в токенах (0 — без ограничения),
+	// LastUsage — раскладка токенов последнего запроса по разделам
+	TokenBudget int
+	LastUsage   PromptUsage
 }
 
-// New создает новый экземпляр LLM клиента
+// New создает новый экземпляр LLM клиента для OpenAI-совместимого Chat Completions API
 func New(apiKey, model, baseURL string) *Client {
-	opts := []option.RequestOption{
-		option.WithAPIKey(apiKey),
-	}
-	// Для OpenRouter/Groq/LocalLLM важно менять BaseURL
-	if baseURL != "" {
-		opts = append(opts, option.WithBaseURL(baseURL))
-	}
+	return NewWithProvider(newChatCompletionsProvider(apiKey, baseURL), model)
+}
 
-	client := openai.NewClient(opts...)
+// NewWithProvider создает клиента поверх произвольного провайдера (см. NewProvider)
+func NewWithProvider(provider Provider, model string) *Client {
 	return &Client{
-		client:        &client,
+		provider:      provider,
 		model:         model,
 		ActionHistory: []entity.ActionRecord{},
 	}
@@ -44,6 +46,17 @@ func (c *Client) Reset() {
 	c.ActionHistory = []entity.ActionRecord{}
 }
 
+// SetMemory обновляет факты из памяти агента. Reset их не трогает:
+// памятью (в том числе между задачами) управляет оркестратор.
+func (c *Client) SetMemory(facts []string) {
+	c.Memory = facts
+}
+
+// SetSchema задает схему результата задачи: по ней строятся аргументы submit_task_result
+func (c *Client) SetSchema(schema map[string]any) {
+	c.Schema = schema
+}
+
 // RecordAction сохраняет результат выполнения действия в историю.
 // Теперь принимает entity.ToolCall целиком, что удобнее.
 func (c *Client) RecordAction(call entity.ToolCall, result string) {
@@ -66,18 +79,17 @@ func (c *Client) Step(ctx context.Context, state *entity.BrowserState, task stri
 		c.Task = task
 	}
 
-	// 2. Формируем контекст сообщений (System + History + Current DOM)
-	// Используем функцию ConstructMessages из prompt.go
-	messages := ConstructMessages(c.Task, c.ActionHistory, state)
+	// 2. Формируем контекст сообщений (System + History + Current DOM) в пределах бюджета
+	messages, usage := ConstructMessagesWithBudget(c.Task, c.Memory, c.ActionHistory, state, c.TokenBudget)
+	c.LastUsage = usage
+	fmt.Printf("📊 Токены промпта: %s\n", usage)
 
 	// 3. Отправляем запрос в LLM
-	// Обрати внимание: используем openai.F() для обертки параметров
-	resp, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+	resp, err := c.provider.Complete(ctx, Request{
 		Model:       c.model,
 		Messages:    messages,
-		Tools:       defineTools(),            // Твоя функция определения тулзов
-		Temperature: openai.Opt[float64](0.1), // Правильный хелпер для float64
-		// ToolChoice: не указываем, по умолчанию "auto"
+		Tools:       defineTools(c.Schema), // Твоя функция определения тулзов
+		Temperature: 0.1,
 	})
 
 	if err != nil {
@@ -85,30 +97,34 @@ func (c *Client) Step(ctx context.Context, state *entity.BrowserState, task stri
 	}
 
 	// 4. Парсим ответ
-	msg := resp.Choices[0].Message
-	return parseResponseToEntity(msg)
+	return parseResponseToEntity(resp)
 }
 
 // --- Вспомогательные функции ---
 
-// parseResponseToEntity конвертирует ответ SDK в твои структуры entity.ToolCall
-func parseResponseToEntity(msg openai.ChatCompletionMessage) ([]entity.ToolCall, error) {
+// parseResponseToEntity конвертирует ответ провайдера в твои структуры entity.ToolCall
+func parseResponseToEntity(resp *Response) ([]entity.ToolCall, error) {
 	// Если тулзов нет, но есть текст - выводим его в лог (для дебага)
-	if len(msg.ToolCalls) == 0 {
-		fmt.Printf("🤖 Agent Reasoning (No Tools): %s\n", msg.Content)
+	if len(resp.ToolCalls) == 0 {
+		fmt.Printf("🤖 Agent Reasoning (No Tools): %s\n", resp.Content)
 		return nil, nil
 	}
 
 	var result []entity.ToolCall
-	reasoning := msg.Content // Мысли агента перед вызовом (CoT)
+	reasoning := resp.Content // Мысли агента перед вызовом (CoT)
 
-	for _, tc := range msg.ToolCalls {
-		var args map[string]interface{}
+	for _, tc := range resp.ToolCalls {
+		args := map[string]interface{}{}
 
-		// Unmarshal аргументов JSON
-		if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
-			// Если модель вернула битый JSON, возвращаем ошибку
-			return nil, fmt.Errorf("failed to parse tool arguments for %s: %w", tc.Function.Name, err)
+		// Unmarshal аргументов JSON (инструменты без параметров могут прийти пустой строкой)
+		if tc.Arguments != "" {
+			if err := json.Unmarshal([]byte(tc.Arguments), &args); err != nil {
+				// Если модель вернула битый JSON, возвращаем ошибку
+				return nil, fmt.Errorf("failed to parse tool arguments for %s: %w", tc.Name, err)
+			}
+			if args == nil {
+				args = map[string]interface{}{} // Аргументы пришли как JSON null
+			}
 		}
 
 		// ВАЖНО: JSON числа приходят как float64.
@@ -120,7 +136,7 @@ func parseResponseToEntity(msg openai.ChatCompletionMessage) ([]entity.ToolCall,
 		}
 
 		result = append(result, entity.ToolCall{
-			Name:      tc.Function.Name,
+			Name:      tc.Name,
 			Args:      args,
 			Reasoning: reasoning, // Прикрепляем общую мысль к каждому действию в пачке
 		})
@@ -2,11 +2,9 @@ package llm
 
 import (
 	"browser-agent/internal/entity"
-	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
-
-	"github.com/openai/openai-go/v3"
 )
 
 const SystemPrompt = `Ты — автономный браузерный агент. Твоя цель — эффективно управлять браузером.
@@ -35,55 +33,147 @@ const SystemPrompt = `Ты — автономный браузерный аге
 
 ### ВАЖНО:
 - Не пиши "Я закончил" текстом. Используй только инструмент "submit_task_result".
-- ID элементов меняются после перезагрузки.
+- Если у "submit_task_result" есть аргумент "result" — заполни его строго по схеме (JSON-объект, а не текст). Если данные не подошли, в истории будет ошибка с путями полей: исправь их и сдай результат снова.
+- ID элементов стабильны: та же кнопка сохраняет ID между шагами, пока она на странице. После "navigate" нумерация начинается заново.
+- Важные факты (данные из писем, промежуточные итоги) сохраняй через "memorize": они всегда видны в блоке AGENT MEMORY, даже когда старые шаги истории уже забыты.
+- Строки DOM без [ID] (например "    <p> Цена 1 200 ₽") — видимый текст страницы в порядке документа: заголовки, абзацы, ячейки таблиц. Кликать по ним нельзя, но читать можно — не открывай элемент ради текста, который уже виден.
+- Текст в DOM обрезан. Чтобы прочитать письмо или статью целиком, вызови "read_text" — текст придет в результате действия в истории.
+- Чтобы собрать много однотипных данных (товары с ценами, строки таблицы, результаты поиска), вызови "extract_table" или "extract_list" с ID элемента внутри таблицы/списка — это один шаг вместо десятков read_text. Извлеченные строки сами попадают в итог задачи.
+- Поля форм: [CHOOSE] — выпадающий список (варианты в {…}), выбирай через "select_option" по тексту варианта, а не кликами; [DATE] и [RANGE] заполняй через "set_value" в формате из скобок; [FILE] — через "upload_file" с именем файла. Если файла нет в каталоге загрузок, не выдумывай имя — сообщи об этом в отчете.
+- Если ссылка открылась в новой вкладке и она больше не нужна — "close_tab".
+- Блок CHANGES SINCE LAST STEP показывает, что изменилось после твоих действий (+ появилось, - исчезло, ~ изменилось). Если изменений нет — действие, скорее всего, не сработало: не повторяй его вслепую.
+- Пометки [above] / [below] — элемент выше или ниже видимой части экрана; строки "--- N elements below the viewport ---" говорят, сколько еще элементов за экраном (и сколько из них не попало в список). Чтобы увидеть их, используй "scroll".
+- Элементы с пометкой [frame: …] лежат во встроенном фрейме другого сайта (оплата, вход) — кликай и вводи в них по ID, как обычно.
 `
 
 // Это чистая функция: вход -> выход. Её легко тестировать.
-// ConstructMessages создает полную цепочку сообщений для отправки в LL
-func ConstructMessages(task string, history []entity.ActionRecord, state *entity.BrowserState) []openai.ChatCompletionMessageParamUnion {
-	messages := []openai.ChatCompletionMessageParamUnion{
-		openai.SystemMessage(SystemPrompt),
+// ConstructMessages создает полную цепочку сообщений для отправки в LLM
+// (в нейтральном формате — в формат API их переводит Provider)
+func ConstructMessages(task string, memory []string, history []entity.ActionRecord, state *entity.BrowserState) []Message {
+	messages, _ := ConstructMessagesWithBudget(task, memory, history, state, 0)
+	return messages
+}
+
+// ConstructMessagesWithBudget — то же, но укладывает промпт в budget токенов
+// (0 — без ограничения). Если всё не влезает, сначала сжимается старая история,
+// потом она сворачивается в сводку, и только потом режется DOM.
+func ConstructMessagesWithBudget(task string, memory []string, history []entity.ActionRecord, state *entity.BrowserState, budget int) ([]Message, PromptUsage) {
+	// --- CURRENT TASK & STATE ---
+	// Память идет в том же сообщении, что и задача: она есть в каждом запросе
+	// и не зависит от того, сколько истории влезло в контекст.
+	taskPart := fmt.Sprintf("CURRENT TASK: %s\n\n", task)
+	memoryPart := memorySection(memory) + "\n\n"
+	statePart := fmt.Sprintf(
+		"CURRENT BROWSER STATE:\n"+
+			"URL: %s\n"+
+			"Title: %s\n\n"+
+			"DOM STRUCTURE (Interactive Elements):\n",
+		state.URL,
+		state.Title,
+	)
+	changesPart := changesSection(state.Changes)
+	dom := state.DOMSummary
+
+	// Скриншот с метками (режим vision) идет картинкой в том же сообщении
+	var images []Image
+	if len(state.Screenshot) > 0 {
+		statePart = "SCREENSHOT: attached. Every element is boxed and labeled with the same ID as in DOM STRUCTURE.\n" + statePart
+		images = append(images, Image{
+			MediaType: http.DetectContentType(state.Screenshot),
+			Data:      state.Screenshot,
+		})
 	}
 
+	usage := PromptUsage{
+		System:     EstimateTokens(SystemPrompt),
+		Task:       EstimateTokens(taskPart) + EstimateTokens(statePart),
+		Memory:     EstimateTokens(memoryPart),
+		Changes:    EstimateTokens(changesPart),
+		Screenshot: len(images) * screenshotTokens,
+		Budget:     budget,
+	}
+	fixed := usage.System + usage.Task + usage.Memory + usage.Changes + usage.Screenshot
+
 	// --- HISTORY BLOCK (JSON Style) ---
-	// Мы меняем формат на JSONL (JSON Lines). Это стандартный формат для логов машин.
+	// Формат JSONL (JSON Lines) — стандартный формат для логов машин.
 	// Модель поймет контекст, но НЕ будет пытаться генерировать такой текст в ответе,
 	// так как она знает, что ее выход - это Tool Calls.
-	if len(history) > 0 {
-		var historyBuilder strings.Builder
-		historyBuilder.WriteString("PREVIOUS ACTIONS LOG (Read-Only Context):\n")
-
-		for i, record := range history {
-			// Создаем временную структуру для чистого JSON
-			logEntry := map[string]interface{}{
-				"step":    i + 1,
-				"thought": record.Reasoning,
-				"action":  record.Action,
-				"args":    record.Args, // Это уже строка JSON, но для лога пойдет
-				"result":  record.Result,
+	historyText, compacted := renderHistory(history, historyFull)
+	if budget > 0 {
+		for _, level := range []historyLevel{historyCompact, historyCollapsed} {
+			if fixed+EstimateTokens(historyText)+EstimateTokens(dom) <= budget {
+				break
 			}
+			historyText, compacted = renderHistory(history, level)
+		}
 
-			// Маршалим в строку
-			jsonBytes, _ := json.Marshal(logEntry)
-			historyBuilder.WriteString(string(jsonBytes) + "\n")
+		domBudget := budget - fixed - EstimateTokens(historyText)
+		if domBudget < minDOMTokens {
+			domBudget = minDOMTokens
 		}
+		dom, usage.TrimmedElements = trimDOM(dom, domBudget)
+	}
+	usage.CompactedSteps = compacted
+	usage.History = EstimateTokens(historyText)
+	usage.DOM = EstimateTokens(dom)
+	usage.Total = fixed + usage.History + usage.DOM
 
-		messages = append(messages, openai.UserMessage(historyBuilder.String()))
+	messages := []Message{
+		{Role: RoleSystem, Content: SystemPrompt},
+	}
+	if historyText != "" {
+		messages = append(messages, Message{Role: RoleUser, Content: historyText})
 	}
+	messages = append(messages, Message{Role: RoleUser, Content: taskPart + memoryPart + changesPart + statePart + dom, Images: images})
 
-	// --- CURRENT TASK & STATE ---
-	userContent := fmt.Sprintf(
-		"CURRENT TASK: %s\n\n"+
-			"CURRENT BROWSER STATE:\n"+
-			"URL: %s\n"+
-			"Title: %s\n\n"+
-			"DOM STRUCTURE (Interactive Elements):\n%s",
-		task,
-		state.URL,
-		state.Title,
-		state.DOMSummary,
-	)
-	messages = append(messages, openai.UserMessage(userContent))
+	return messages, usage
+}
 
-	return messages
+// memorySection — блок с фактами, сохраненными через memorize
+func memorySection(memory []string) string {
+	var sb strings.Builder
+	sb.WriteString("AGENT MEMORY (facts saved with memorize):")
+	if len(memory) == 0 {
+		sb.WriteString("\n(empty)")
+	}
+	for _, fact := range memory {
+		sb.WriteString("\n- " + fact)
+	}
+	return sb.String()
+}
+
+// maxChangeLines — сколько строк каждого вида изменений показывать модели
+const maxChangeLines = 15
+
+// changesSection — что изменилось после прошлого шага (пусто, если сравнивать не с чем)
+func changesSection(changes *entity.DOMChanges) string {
+	if changes == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("CHANGES SINCE LAST STEP:\n")
+	if changes.Empty() {
+		sb.WriteString("(no changes on the page — the last action may have had no effect)\n\n")
+		return sb.String()
+	}
+
+	if changes.PrevURL != "" {
+		sb.WriteString("URL changed from " + changes.PrevURL + "\n")
+	}
+	writeChangeLines(&sb, "+ ", changes.Added)
+	writeChangeLines(&sb, "- ", changes.Removed)
+	writeChangeLines(&sb, "~ ", changes.Changed)
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+func writeChangeLines(sb *strings.Builder, prefix string, lines []string) {
+	for i, line := range lines {
+		if i == maxChangeLines {
+			fmt.Fprintf(sb, "%s... and %d more\n", prefix, len(lines)-maxChangeLines)
+			return
+		}
+		sb.WriteString(prefix + line + "\n")
+	}
 }
@@ -2,34 +2,15 @@ package llm
 
 import (
 	"browser-agent/internal/entity"
-	"encoding/json"
+	"fmt"
 	"strings"
 	"testing"
-
-	"github.com/openai/openai-go/v3"
 )
 
-// Helper: Превращаем сообщение в JSON и достаем контент.
-// Это работает для ЛЮБОГО типа сообщения (System, User, Assistant),
-// потому что SDK гарантирует правильный JSON маршалинг.
-func extractContent(t *testing.T, msg openai.ChatCompletionMessageParamUnion) string {
-	// 1. Маршалим структуру SDK в байты JSON
-	bytes, err := json.Marshal(msg)
-	if err != nil {
-		t.Fatalf("Failed to marshal message: %v", err)
-	}
-
-	// 2. Анмаршалим в простую временную структуру, чтобы достать текст
-	var temp struct {
-		Role    string `json:"role"`
-		Content string `json:"content"`
-	}
-
-	if err := json.Unmarshal(bytes, &temp); err != nil {
-		t.Fatalf("Failed to unmarshal JSON content: %v", err)
-	}
-
-	return temp.Content
+// Helper: достаем текст сообщения (формат не зависит от провайдера)
+func extractContent(t *testing.T, msg Message) string {
+	t.Helper()
+	return msg.Content
 }
 
 func TestConstructMessages_FirstStep(t *testing.T) {
@@ -42,7 +23,7 @@ func TestConstructMessages_FirstStep(t *testing.T) {
 		DOMSummary: "[1] <input> Search",
 	}
 
-	msgs := ConstructMessages(task, history, state)
+	msgs := ConstructMessages(task, nil, history, state)
 
 	if len(msgs) != 2 {
 		t.Errorf("Expected 2 messages, got %d", len(msgs))
@@ -51,7 +32,7 @@ func TestConstructMessages_FirstStep(t *testing.T) {
 	// Проверяем System Prompt
 	sysContent := extractContent(t, msgs[0])
 	// Сравниваем начало строки, чтобы не падать из-за пробелов
-	if !strings.Contains(sysContent, "Ты — автономный AI-агент") {
+	if !strings.Contains(sysContent, "Ты — автономный браузерный агент") {
 		t.Error("System prompt mismatch")
 	}
 
@@ -65,7 +46,7 @@ func TestConstructMessages_FirstStep(t *testing.T) {
 	if !strings.Contains(userContent, "google.com") {
 		t.Error("URL missing in prompt")
 	}
-	if strings.Contains(userContent, "HISTORY OF ACTIONS") {
+	if strings.Contains(userContent, "PREVIOUS ACTIONS LOG") {
 		t.Error("History should be empty on first step")
 	}
 }
@@ -87,7 +68,7 @@ func TestConstructMessages_WithHistory(t *testing.T) {
 		DOMSummary: "[1] <text> Входящие пусты",
 	}
 
-	msgs := ConstructMessages(task, history, state)
+	msgs := ConstructMessages(task, nil, history, state)
 
 	if len(msgs) != 3 {
 		t.Errorf("Expected 3 messages, got %d", len(msgs))
@@ -97,7 +78,7 @@ func TestConstructMessages_WithHistory(t *testing.T) {
 	historyContent := extractContent(t, msgs[1])
 	t.Logf("\n--- [TEST LOG] History Message ---\n%s\n----------------------------------", historyContent)
 
-	if !strings.Contains(historyContent, "HISTORY OF ACTIONS") {
+	if !strings.Contains(historyContent, "PREVIOUS ACTIONS LOG") {
 		t.Error("Header missing")
 	}
 	if !strings.Contains(historyContent, "Вижу письмо от мамы") {
@@ -113,3 +94,225 @@ func TestConstructMessages_WithHistory(t *testing.T) {
 		t.Error("Current DOM missing")
 	}
 }
+
+func TestConstructMessages_WithMemory(t *testing.T) {
+	// Сценарий 3: в памяти есть факты — они должны попасть в текущее сообщение,
+	// а не в историю, которую потом можно обрезать
+	memory := []string{"Код подтверждения: 4821", "Письмо от банка уже прочитано"}
+	state := &entity.BrowserState{
+		URL:        "https://mail.yandex.ru",
+		Title:      "Входящие",
+		DOMSummary: "[1] <link> [NAVIGATE] Входящие",
+	}
+
+	msgs := ConstructMessages("Ввести код из письма", memory, nil, state)
+
+	if len(msgs) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(msgs))
+	}
+
+	userContent := extractContent(t, msgs[1])
+	if !strings.Contains(userContent, "AGENT MEMORY") {
+		t.Error("Memory section missing")
+	}
+	for _, fact := range memory {
+		if !strings.Contains(userContent, "- "+fact) {
+			t.Errorf("Fact %q missing in prompt", fact)
+		}
+	}
+}
+
+// longHistory — n шагов с длинными мыслями; шаг errorStep (с 1) завершился ошибкой
+func longHistory(n, errorStep int) []entity.ActionRecord {
+	history := make([]entity.ActionRecord, n)
+	for i := range history {
+		history[i] = entity.ActionRecord{
+			Reasoning: fmt.Sprintf("мысль шага %d: %s", i+1, strings.Repeat("рассуждение ", 40)),
+			Action:    "click",
+			Args:      fmt.Sprintf(`{"id":%d}`, i+1),
+			Result:    fmt.Sprintf("Clicked element %d", i+1),
+		}
+	}
+	history[errorStep-1].Result = "Error: element not found"
+	return history
+}
+
+func TestSystemPrompt_WithinBudget(t *testing.T) {
+	// SystemPrompt идет в каждый запрос, и каждая новая строка в нем отнимает
+	// место у истории и DOM. Подсказки по инструментам — в их описаниях.
+	const maxSystemPromptTokens = 1300
+	if got := EstimateTokens(SystemPrompt); got > maxSystemPromptTokens {
+		t.Errorf("SystemPrompt is %d tokens, max %d", got, maxSystemPromptTokens)
+	}
+}
+
+func TestConstructMessages_BudgetCompactsHistory(t *testing.T) {
+	// Сценарий 4: длинная задача — старые шаги сжимаются, последние и ошибки остаются целиком
+	history := longHistory(20, 3)
+	state := &entity.BrowserState{URL: "https://shop.test", Title: "Магазин", DOMSummary: "[1] <button> [ACTION] Купить\n"}
+
+	full := ConstructMessages("Купить слона", nil, history, state)
+	msgs, usage := ConstructMessagesWithBudget("Купить слона", nil, history, state, 3000)
+
+	if EstimateTokens(full[1].Content) <= usage.History {
+		t.Fatalf("History was not compacted: %d tokens", usage.History)
+	}
+	if usage.CompactedSteps != 14 {
+		t.Errorf("Expected 14 compacted steps (20 - 5 recent - 1 error), got %d", usage.CompactedSteps)
+	}
+	if usage.Total > usage.Budget {
+		t.Errorf("Prompt exceeds budget: %s", usage)
+	}
+
+	historyContent := extractContent(t, msgs[1])
+	for _, step := range []int{3, 16, 20} {
+		if !strings.Contains(historyContent, fmt.Sprintf("мысль шага %d:", step)) {
+			t.Errorf("Step %d must be kept verbatim", step)
+		}
+	}
+	if strings.Contains(historyContent, "мысль шага 1:") {
+		t.Error("Old step reasoning must be compacted")
+	}
+	if !strings.Contains(extractContent(t, msgs[2]), "Купить") {
+		t.Error("DOM must not be trimmed when compacting history is enough")
+	}
+}
+
+func TestConstructMessages_BudgetTrimsDOM(t *testing.T) {
+	// Сценарий 5: огромный DOM режется по целым строкам
+	var dom strings.Builder
+	for i := 1; i <= 3000; i++ {
+		fmt.Fprintf(&dom, "[%d] <link> [NAVIGATE] Товар номер %d\n", i, i)
+	}
+	state := &entity.BrowserState{URL: "https://shop.test", Title: "Каталог", DOMSummary: dom.String()}
+
+	msgs, usage := ConstructMessagesWithBudget("Найти товар", nil, nil, state, 4000)
+
+	if usage.TrimmedElements == 0 {
+		t.Fatal("Expected DOM to be trimmed")
+	}
+	if usage.Total > usage.Budget {
+		t.Errorf("Prompt exceeds budget: %s", usage)
+	}
+
+	userContent := extractContent(t, msgs[1])
+	if !strings.Contains(userContent, "[1] <link> [NAVIGATE] Товар номер 1\n") {
+		t.Error("First elements must be kept")
+	}
+	if !strings.Contains(userContent, fmt.Sprintf("%d more elements trimmed", usage.TrimmedElements)) {
+		t.Error("Trim marker missing")
+	}
+}
+
+func TestConstructMessages_BudgetTrimsFarFromViewport(t *testing.T) {
+	// Страница прокручена: сотни элементов выше экрана, экран, сотни ниже
+	var dom strings.Builder
+	dom.WriteString("--- 400 elements above the viewport — scroll up ---\n")
+	for i := 1; i <= 400; i++ {
+		fmt.Fprintf(&dom, "[%d] <link> [NAVIGATE] Товар номер %d [above]\n", i, i)
+	}
+	for i := 401; i <= 420; i++ {
+		fmt.Fprintf(&dom, "[%d] <link> [NAVIGATE] Товар номер %d\n", i, i)
+	}
+	for i := 421; i <= 820; i++ {
+		fmt.Fprintf(&dom, "[%d] <link> [NAVIGATE] Товар номер %d [below]\n", i, i)
+	}
+	dom.WriteString("--- 400 elements below the viewport — scroll down ---\n")
+	state := &entity.BrowserState{URL: "https://shop.test", Title: "Каталог", DOMSummary: dom.String()}
+
+	msgs, usage := ConstructMessagesWithBudget("Найти товар", nil, nil, state, 3000)
+
+	if usage.TrimmedElements == 0 {
+		t.Fatal("Expected DOM to be trimmed")
+	}
+	if usage.Total > usage.Budget {
+		t.Errorf("Prompt exceeds budget: %s", usage)
+	}
+
+	userContent := extractContent(t, msgs[1])
+	for _, want := range []string{
+		"[401] <link> [NAVIGATE] Товар номер 401\n",
+		"[420] <link> [NAVIGATE] Товар номер 420\n",
+		"[400] <link> [NAVIGATE] Товар номер 400 [above]\n", // ближайшие к экрану остаются
+		"[421] <link> [NAVIGATE] Товар номер 421 [below]\n",
+		"--- 400 elements above the viewport",
+		"--- 400 elements below the viewport",
+	} {
+		if !strings.Contains(userContent, want) {
+			t.Errorf("Expected %q to be kept", want)
+		}
+	}
+	for _, far := range []string{"[1] <link>", "[820] <link>"} {
+		if strings.Contains(userContent, far) {
+			t.Errorf("Farthest element %q must be trimmed first", far)
+		}
+	}
+}
+
+func TestConstructMessages_NoBudgetKeepsEverything(t *testing.T) {
+	history := longHistory(20, 3)
+	state := &entity.BrowserState{URL: "https://shop.test", Title: "Магазин", DOMSummary: "[1] <button> [ACTION] Купить\n"}
+
+	msgs, usage := ConstructMessagesWithBudget("Купить слона", nil, history, state, 0)
+
+	if usage.CompactedSteps != 0 || usage.TrimmedElements != 0 {
+		t.Errorf("Nothing must be compacted without budget: %s", usage)
+	}
+	if !strings.Contains(extractContent(t, msgs[1]), "мысль шага 1:") {
+		t.Error("Full history expected without budget")
+	}
+}
+
+func TestConstructMessages_Screenshot(t *testing.T) {
+	// Сценарий 6: режим vision — скриншот идет картинкой в сообщении с DOM
+	png := []byte("\x89PNG\r\n\x1a\nfake")
+	state := &entity.BrowserState{URL: "https://canvas.test", Title: "Холст", DOMSummary: "[1] <clickable> [CLICK] Item\n", Screenshot: png}
+
+	msgs, usage := ConstructMessagesWithBudget("Нажми на красный круг", nil, nil, state, 0)
+
+	last := msgs[len(msgs)-1]
+	if len(last.Images) != 1 || last.Images[0].MediaType != "image/png" {
+		t.Fatalf("Expected one PNG image, got %+v", last.Images)
+	}
+	if !strings.Contains(last.Content, "SCREENSHOT: attached") {
+		t.Error("Screenshot note missing")
+	}
+	if usage.Screenshot == 0 {
+		t.Error("Screenshot tokens must be counted")
+	}
+	if len(msgs[0].Images) != 0 {
+		t.Error("System prompt must not carry images")
+	}
+}
+
+func TestConstructMessages_Changes(t *testing.T) {
+	// Сценарий 7: после клика по чекбоксу модель видит, что он отметился
+	state := &entity.BrowserState{
+		URL:        "https://mail.test",
+		Title:      "Входящие",
+		DOMSummary: "[5] <checkbox> [SELECT] Спам (V)\n",
+		Changes: &entity.DOMChanges{
+			Changed: []string{"[5] <checkbox> [SELECT] Спам ( ) -> [SELECT] Спам (V)"},
+		},
+	}
+
+	msgs := ConstructMessages("Отметь спам", nil, nil, state)
+	userContent := extractContent(t, msgs[len(msgs)-1])
+	if !strings.Contains(userContent, "CHANGES SINCE LAST STEP:\n~ [5] <checkbox> [SELECT] Спам ( ) -> [SELECT] Спам (V)\n") {
+		t.Errorf("Changes section missing:\n%s", userContent)
+	}
+
+	// Пустой diff — явный сигнал, что действие не сработало
+	state.Changes = &entity.DOMChanges{}
+	msgs = ConstructMessages("Отметь спам", nil, nil, state)
+	if !strings.Contains(extractContent(t, msgs[len(msgs)-1]), "no changes on the page") {
+		t.Error("Empty changes must be reported")
+	}
+
+	// Первый шаг — сравнивать не с чем, блока нет
+	state.Changes = nil
+	msgs = ConstructMessages("Отметь спам", nil, nil, state)
+	if strings.Contains(extractContent(t, msgs[len(msgs)-1]), "CHANGES SINCE LAST STEP") {
+		t.Error("No changes section expected on the first step")
+	}
+}
@@ -0,0 +1,142 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Имена провайдеров для конфига (LLM_PROVIDER)
+const (
+	ProviderOpenAI          = "openai"           // Chat Completions (OpenAI, Groq, OpenRouter, vLLM...)
+	ProviderOpenAIResponses = "openai-responses" // OpenAI Responses API
+	ProviderAnthropic       = "anthropic"        // Anthropic Messages API (tool use)
+	ProviderOllama          = "ollama"           // Нативный API Ollama (/api/chat)
+)
+
+type Role string
+
+const (
+	RoleSystem Role = "system"
+	RoleUser   Role = "user"
+)
+
+// Message — сообщение промпта в нейтральном формате
+type Message struct {
+	Role    Role
+	Content string
+	Images  []Image // Картинки после текста (только для vision-моделей)
+}
+
+// Image — картинка в сообщении (скриншот страницы)
+type Image struct {
+	MediaType string // image/jpeg, image/png
+	Data      []byte
+}
+
+// Base64 — данные картинки для JSON-полей провайдеров
+func (i Image) Base64() string {
+	return base64.StdEncoding.EncodeToString(i.Data)
+}
+
+// DataURL — картинка как data: URL (OpenAI Chat Completions и Responses)
+func (i Image) DataURL() string {
+	return "data:" + i.MediaType + ";base64," + i.Base64()
+}
+
+// Request — один запрос к модели
+type Request struct {
+	Model       string
+	Messages    []Message
+	Tools       []ToolSpec
+	Temperature float64
+}
+
+// RawToolCall — вызов инструмента, как его вернул провайдер.
+// Arguments — JSON-объект строкой (у всех API он приводится к этому виду).
+type RawToolCall struct {
+	Name      string
+	Arguments string
+}
+
+// Response — ответ модели: текст (мысли) и вызовы инструментов
+type Response struct {
+	Content   string
+	ToolCalls []RawToolCall
+}
+
+// Provider — адаптер конкретного LLM API под Client (и интерфейс agent.Brain)
+type Provider interface {
+	Complete(ctx context.Context, req Request) (*Response, error)
+}
+
+// NewProvider создает адаптер по имени из конфига
+func NewProvider(name, apiKey, baseURL string) (Provider, error) {
+	switch name {
+	case "", ProviderOpenAI:
+		return newChatCompletionsProvider(apiKey, baseURL), nil
+	case ProviderOpenAIResponses:
+		return newResponsesProvider(apiKey, baseURL), nil
+	case ProviderAnthropic:
+		return newAnthropicProvider(apiKey, baseURL), nil
+	case ProviderOllama:
+		return newOllamaProvider(baseURL), nil
+	default:
+		return nil, fmt.Errorf("unknown llm provider %q", name)
+	}
+}
+
+// splitSystem отделяет системный промпт (Anthropic и Responses API принимают его отдельным полем)
+func splitSystem(messages []Message) (string, []Message) {
+	var system []string
+	var rest []Message
+	for _, m := range messages {
+		if m.Role == RoleSystem {
+			system = append(system, m.Content)
+			continue
+		}
+		rest = append(rest, m)
+	}
+	return strings.Join(system, "\n\n"), rest
+}
+
+// postJSON — общий HTTP-клиент для адаптеров без SDK
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAnthropicURL = "https://api.anthropic.com"
+	anthropicVersion    = "2023-06-01"
+	anthropicMaxTokens  = 4096
+)
+
+// anthropicProvider — Anthropic Messages API с tool use (POST /v1/messages)
+type anthropicProvider struct {
+	apiKey  string
+	baseURL string
+	http    *http.Client
+}
+
+func newAnthropicProvider(apiKey, baseURL string) *anthropicProvider {
+	if baseURL == "" {
+		baseURL = defaultAnthropicURL
+	}
+	return &anthropicProvider{
+		apiKey:  apiKey,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// anthropicMessage.Content — строка или, если есть картинки, список блоков
+type anthropicMessage struct {
+	Role    Role `json:"role"`
+	Content any  `json:"content"`
+}
+
+type anthropicBlock struct {
+	Type   string                `json:"type"` // text | image
+	Text   string                `json:"text,omitempty"`
+	Source *anthropicImageSource `json:"source,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"` // base64
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	MaxTokens   int                `json:"max_tokens"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	Tools       []anthropicTool    `json:"tools,omitempty"`
+	Temperature float64            `json:"temperature"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type  string          `json:"type"` // text | tool_use
+		Text  string          `json:"text"`
+		Name  string          `json:"name"`
+		Input json.RawMessage `json:"input"`
+	} `json:"content"`
+}
+
+func (p *anthropicProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	system, messages := splitSystem(req.Messages)
+
+	body := anthropicRequest{
+		Model:       req.Model,
+		MaxTokens:   anthropicMaxTokens,
+		System:      system,
+		Temperature: req.Temperature,
+	}
+	// Messages API ждет чередования ролей — подряд идущие user-сообщения склеиваем
+	var merged []Message
+	for _, m := range messages {
+		if n := len(merged); n > 0 && merged[n-1].Role == m.Role {
+			merged[n-1].Content += "\n\n" + m.Content
+			merged[n-1].Images = append(merged[n-1].Images, m.Images...)
+			continue
+		}
+		merged = append(merged, m)
+	}
+	for _, m := range merged {
+		msg := anthropicMessage{Role: m.Role, Content: m.Content}
+		if len(m.Images) > 0 {
+			blocks := []anthropicBlock{{Type: "text", Text: m.Content}}
+			for _, img := range m.Images {
+				blocks = append(blocks, anthropicBlock{Type: "image", Source: &anthropicImageSource{
+					Type:      "base64",
+					MediaType: img.MediaType,
+					Data:      img.Base64(),
+				}})
+			}
+			msg.Content = blocks
+		}
+		body.Messages = append(body.Messages, msg)
+	}
+	for _, t := range req.Tools {
+		body.Tools = append(body.Tools, anthropicTool{
+			Name:        t.Name,
+			Description: t.Description,
+			InputSchema: t.Parameters,
+		})
+	}
+
+	var resp anthropicResponse
+	headers := map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+	if err := postJSON(ctx, p.http, p.baseURL+"/v1/messages", headers, body, &resp); err != nil {
+		return nil, err
+	}
+
+	out := &Response{}
+	var text []string
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			text = append(text, block.Text)
+		case "tool_use":
+			out.ToolCalls = append(out.ToolCalls, RawToolCall{Name: block.Name, Arguments: string(block.Input)})
+		}
+	}
+	out.Content = strings.Join(text, "\n")
+	return out, nil
+}
@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultOllamaURL = "http://localhost:11434"
+
+// ollamaProvider — нативный API Ollama (POST /api/chat, stream=false)
+type ollamaProvider struct {
+	baseURL string
+	http    *http.Client
+}
+
+func newOllamaProvider(baseURL string) *ollamaProvider {
+	if baseURL == "" {
+		baseURL = defaultOllamaURL
+	}
+	return &ollamaProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		// Локальные модели бывают медленными — даем больше времени
+		http: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+type ollamaMessage struct {
+	Role    Role     `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"` // base64 без data: префикса
+}
+
+type ollamaTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description,omitempty"`
+		Parameters  map[string]any `json:"parameters"`
+	} `json:"function"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []ollamaTool    `json:"tools,omitempty"`
+	Stream   bool            `json:"stream"`
+	Options  map[string]any  `json:"options,omitempty"`
+}
+
+type ollamaResponse struct {
+	Message struct {
+		Content   string `json:"content"`
+		ToolCalls []struct {
+			Function struct {
+				Name      string          `json:"name"`
+				Arguments json.RawMessage `json:"arguments"` // Объект, а не строка
+			} `json:"function"`
+		} `json:"tool_calls"`
+	} `json:"message"`
+}
+
+func (p *ollamaProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	body := ollamaRequest{
+		Model:   req.Model,
+		Stream:  false,
+		Options: map[string]any{"temperature": req.Temperature},
+	}
+	for _, m := range req.Messages {
+		msg := ollamaMessage{Role: m.Role, Content: m.Content}
+		for _, img := range m.Images {
+			msg.Images = append(msg.Images, img.Base64())
+		}
+		body.Messages = append(body.Messages, msg)
+	}
+	for _, t := range req.Tools {
+		tool := ollamaTool{Type: "function"}
+		tool.Function.Name = t.Name
+		tool.Function.Description = t.Description
+		tool.Function.Parameters = t.Parameters
+		body.Tools = append(body.Tools, tool)
+	}
+
+	var resp ollamaResponse
+	if err := postJSON(ctx, p.http, p.baseURL+"/api/chat", nil, body, &resp); err != nil {
+		return nil, err
+	}
+
+	out := &Response{Content: resp.Message.Content}
+	for _, tc := range resp.Message.ToolCalls {
+		out.ToolCalls = append(out.ToolCalls, RawToolCall{
+			Name:      tc.Function.Name,
+			Arguments: string(tc.Function.Arguments),
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// chatCompletionsProvider — OpenAI-совместимый Chat Completions через openai-go
+type chatCompletionsProvider struct {
+	client *openai.Client
+}
+
+func newChatCompletionsProvider(apiKey, baseURL string) *chatCompletionsProvider {
+	opts := []option.RequestOption{
+		option.WithAPIKey(apiKey),
+	}
+	// Для OpenRouter/Groq/LocalLLM важно менять BaseURL
+	if baseURL != "" {
+		opts = append(opts, option.WithBaseURL(baseURL))
+	}
+
+	client := openai.NewClient(opts...)
+	return &chatCompletionsProvider{client: &client}
+}
+
+func (p *chatCompletionsProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	resp, err := p.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+		Model:       req.Model,
+		Messages:    toChatMessages(req.Messages),
+		Tools:       toChatTools(req.Tools),
+		Temperature: openai.Opt(req.Temperature), // Правильный хелпер для float64
+		// ToolChoice: не указываем, по умолчанию "auto"
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("empty choices in response")
+	}
+
+	msg := resp.Choices[0].Message
+	out := &Response{Content: msg.Content}
+	for _, tc := range msg.ToolCalls {
+		out.ToolCalls = append(out.ToolCalls, RawToolCall{
+			Name:      tc.Function.Name,
+			Arguments: tc.Function.Arguments,
+		})
+	}
+	return out, nil
+}
+
+func toChatMessages(messages []Message) []openai.ChatCompletionMessageParamUnion {
+	result := make([]openai.ChatCompletionMessageParamUnion, 0, len(messages))
+	for _, m := range messages {
+		switch {
+		case m.Role == RoleSystem:
+			result = append(result, openai.SystemMessage(m.Content))
+		case len(m.Images) > 0:
+			// Текст и картинки одним сообщением из нескольких частей
+			parts := []openai.ChatCompletionContentPartUnionParam{openai.TextContentPart(m.Content)}
+			for _, img := range m.Images {
+				parts = append(parts, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+					URL: img.DataURL(),
+				}))
+			}
+			result = append(result, openai.UserMessage(parts))
+		default:
+			result = append(result, openai.UserMessage(m.Content))
+		}
+	}
+	return result
+}
+
+func toChatTools(tools []ToolSpec) []openai.ChatCompletionToolUnionParam {
+	result := make([]openai.ChatCompletionToolUnionParam, 0, len(tools))
+	for _, t := range tools {
+		result = append(result, openai.ChatCompletionFunctionTool(openai.FunctionDefinitionParam{
+			Name:        t.Name,
+			Description: openai.String(t.Description),
+			Parameters:  openai.FunctionParameters(t.Parameters),
+		}))
+	}
+	return result
+}
@@ -0,0 +1,120 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultOpenAIURL = "https://api.openai.com/v1"
+
+// responsesProvider — OpenAI Responses API (POST /responses)
+type responsesProvider struct {
+	apiKey  string
+	baseURL string
+	http    *http.Client
+}
+
+func newResponsesProvider(apiKey, baseURL string) *responsesProvider {
+	if baseURL == "" {
+		baseURL = defaultOpenAIURL
+	}
+	return &responsesProvider{
+		apiKey:  apiKey,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// responsesInput.Content — строка или, если есть картинки, список частей
+type responsesInput struct {
+	Role    Role `json:"role"`
+	Content any  `json:"content"`
+}
+
+type responsesContentPart struct {
+	Type     string `json:"type"` // input_text | input_image
+	Text     string `json:"text,omitempty"`
+	ImageURL string `json:"image_url,omitempty"`
+}
+
+type responsesTool struct {
+	Type        string         `json:"type"`
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters"`
+	Strict      bool           `json:"strict"`
+}
+
+type responsesRequest struct {
+	Model        string           `json:"model"`
+	Instructions string           `json:"instructions,omitempty"`
+	Input        []responsesInput `json:"input"`
+	Tools        []responsesTool  `json:"tools,omitempty"`
+	Temperature  float64          `json:"temperature"`
+}
+
+type responsesResponse struct {
+	Output []struct {
+		Type      string `json:"type"` // message | function_call | reasoning
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+		Content   []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	} `json:"output"`
+}
+
+func (p *responsesProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	system, messages := splitSystem(req.Messages)
+
+	body := responsesRequest{
+		Model:        req.Model,
+		Instructions: system,
+		Temperature:  req.Temperature,
+	}
+	for _, m := range messages {
+		input := responsesInput{Role: m.Role, Content: m.Content}
+		if len(m.Images) > 0 {
+			parts := []responsesContentPart{{Type: "input_text", Text: m.Content}}
+			for _, img := range m.Images {
+				parts = append(parts, responsesContentPart{Type: "input_image", ImageURL: img.DataURL()})
+			}
+			input.Content = parts
+		}
+		body.Input = append(body.Input, input)
+	}
+	for _, t := range req.Tools {
+		body.Tools = append(body.Tools, responsesTool{
+			Type:        "function",
+			Name:        t.Name,
+			Description: t.Description,
+			Parameters:  t.Parameters,
+		})
+	}
+
+	var resp responsesResponse
+	headers := map[string]string{"Authorization": "Bearer " + p.apiKey}
+	if err := postJSON(ctx, p.http, p.baseURL+"/responses", headers, body, &resp); err != nil {
+		return nil, err
+	}
+
+	out := &Response{}
+	var text []string
+	for _, item := range resp.Output {
+		switch item.Type {
+		case "message":
+			for _, c := range item.Content {
+				if c.Type == "output_text" {
+					text = append(text, c.Text)
+				}
+			}
+		case "function_call":
+			out.ToolCalls = append(out.ToolCalls, RawToolCall{Name: item.Name, Arguments: item.Arguments})
+		}
+	}
+	out.Content = strings.Join(text, "\n")
+	return out, nil
+}
@@ -0,0 +1,212 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"browser-agent/internal/keyboard"
+)
+
+// Каждый адаптер гоняем против фейкового API: проверяем, что запрос ушел
+// по нужному пути, а tool calls из ответа приведены к общему виду.
+func TestProviders_ParseToolCalls(t *testing.T) {
+	cases := []struct {
+		name     string
+		path     string
+		response string
+		provider func(url string) Provider
+	}{
+		{
+			name:     ProviderOpenAIResponses,
+			path:     "/responses",
+			response: `{"output":[{"type":"message","content":[{"type":"output_text","text":"Жму поиск"}]},{"type":"function_call","name":"click","arguments":"{\"id\":7}"}]}`,
+			provider: func(url string) Provider { return newResponsesProvider("key", url) },
+		},
+		{
+			name:     ProviderAnthropic,
+			path:     "/v1/messages",
+			response: `{"content":[{"type":"text","text":"Жму поиск"},{"type":"tool_use","name":"click","input":{"id":7}}]}`,
+			provider: func(url string) Provider { return newAnthropicProvider("key", url) },
+		},
+		{
+			name:     ProviderOllama,
+			path:     "/api/chat",
+			response: `{"message":{"content":"Жму поиск","tool_calls":[{"function":{"name":"click","arguments":{"id":7}}}]}}`,
+			provider: func(url string) Provider { return newOllamaProvider(url) },
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var body map[string]any
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tc.path {
+					t.Errorf("Expected path %s, got %s", tc.path, r.URL.Path)
+				}
+				data, _ := io.ReadAll(r.Body)
+				if err := json.Unmarshal(data, &body); err != nil {
+					t.Errorf("Request is not JSON: %v", err)
+				}
+				_, _ = io.WriteString(w, tc.response)
+			}))
+			defer srv.Close()
+
+			resp, err := tc.provider(srv.URL).Complete(context.Background(), Request{
+				Model: "test-model",
+				Messages: []Message{
+					{Role: RoleSystem, Content: "system"},
+					{Role: RoleUser, Content: "history"},
+					{Role: RoleUser, Content: "state"},
+				},
+				Tools: defineTools(nil),
+			})
+			if err != nil {
+				t.Fatalf("Complete failed: %v", err)
+			}
+
+			if body["model"] != "test-model" {
+				t.Errorf("Model not sent: %v", body["model"])
+			}
+			if tools, _ := body["tools"].([]any); len(tools) != len(defineTools(nil)) {
+				t.Errorf("Expected %d tools in request, got %d", len(defineTools(nil)), len(tools))
+			}
+
+			calls, err := parseResponseToEntity(resp)
+			if err != nil {
+				t.Fatalf("parse failed: %v", err)
+			}
+			if len(calls) != 1 || calls[0].Name != "click" || calls[0].Args["id"] != 7 {
+				t.Errorf("Unexpected tool calls: %+v", calls)
+			}
+			if calls[0].Reasoning != "Жму поиск" {
+				t.Errorf("Reasoning lost: %q", calls[0].Reasoning)
+			}
+		})
+	}
+}
+
+func TestAnthropicProvider_MergesConsecutiveUserMessages(t *testing.T) {
+	var body anthropicRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, _ = io.WriteString(w, `{"content":[]}`)
+	}))
+	defer srv.Close()
+
+	_, err := newAnthropicProvider("key", srv.URL).Complete(context.Background(), Request{
+		Messages: []Message{
+			{Role: RoleSystem, Content: "system"},
+			{Role: RoleUser, Content: "history"},
+			{Role: RoleUser, Content: "state"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+
+	if body.System != "system" {
+		t.Errorf("System prompt must go to a separate field, got %q", body.System)
+	}
+	if len(body.Messages) != 1 || body.Messages[0].Content != "history\n\nstate" {
+		t.Errorf("Expected one merged user message, got %+v", body.Messages)
+	}
+}
+
+// Скриншот (vision) должен уйти картинкой в формате каждого API
+func TestProviders_SendImages(t *testing.T) {
+	img := Image{MediaType: "image/jpeg", Data: []byte("fake-jpeg")}
+
+	cases := []struct {
+		name     string
+		want     string // Фрагмент тела запроса с картинкой
+		provider func(url string) Provider
+	}{
+		{ProviderOpenAIResponses, `"type":"input_image","image_url":"` + img.DataURL() + `"`, func(url string) Provider { return newResponsesProvider("key", url) }},
+		{ProviderAnthropic, `"source":{"type":"base64","media_type":"image/jpeg","data":"` + img.Base64() + `"}`, func(url string) Provider { return newAnthropicProvider("key", url) }},
+		{ProviderOllama, `"images":["` + img.Base64() + `"]`, func(url string) Provider { return newOllamaProvider(url) }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var body string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, _ := io.ReadAll(r.Body)
+				body = string(data)
+				_, _ = io.WriteString(w, `{}`)
+			}))
+			defer srv.Close()
+
+			_, err := tc.provider(srv.URL).Complete(context.Background(), Request{
+				Messages: []Message{
+					{Role: RoleSystem, Content: "system"},
+					{Role: RoleUser, Content: "state", Images: []Image{img}},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Complete failed: %v", err)
+			}
+			if !strings.Contains(body, tc.want) {
+				t.Errorf("Image not found in request body:\n%s", body)
+			}
+		})
+	}
+}
+
+func TestDefineTools_ResultSchema(t *testing.T) {
+	submit := func(tools []ToolSpec) map[string]any {
+		for _, tool := range tools {
+			if tool.Name == "submit_task_result" {
+				return tool.Parameters
+			}
+		}
+		t.Fatal("submit_task_result not defined")
+		return nil
+	}
+
+	plain := submit(defineTools(nil))
+	if _, ok := plain["properties"].(map[string]any)["result"]; ok {
+		t.Error("Without schema submit_task_result must not have 'result'")
+	}
+
+	resultSchema := map[string]any{"type": "array", "items": map[string]any{"type": "string"}}
+	params := submit(defineTools(resultSchema))
+	result, ok := params["properties"].(map[string]any)["result"].(map[string]any)
+	if !ok || result["type"] != "array" || result["items"] == nil {
+		t.Fatalf("Schema not embedded into 'result': %v", params)
+	}
+	if required := params["required"].([]string); len(required) != 2 || required[1] != "result" {
+		t.Errorf("'result' must be required, got %v", required)
+	}
+	if _, ok := resultSchema["description"]; ok {
+		t.Error("Caller's schema must not be modified")
+	}
+}
+
+// Всё, что описание press предлагает модели, PressKey должен принять
+func TestDefineTools_PressKeysAreParsed(t *testing.T) {
+	var key map[string]any
+	for _, tool := range defineTools(nil) {
+		if tool.Name == "press" {
+			key = tool.Parameters["properties"].(map[string]any)["key"].(map[string]any)
+		}
+	}
+	if key == nil {
+		t.Fatal("press not defined")
+	}
+
+	description := key["description"].(string)
+	advertised := append(append(keyboard.Names(), keyboard.ModifierNames()...), keyboard.Examples...)
+	for _, name := range advertised {
+		if !strings.Contains(description, name) {
+			t.Errorf("Key %q is not listed in the press description", name)
+		}
+		if _, err := keyboard.Parse(name); err != nil {
+			t.Errorf("Advertised key %q is rejected: %v", name, err)
+		}
+	}
+}
@@ -1,14 +1,29 @@
 package llm
 
-import "github.com/openai/openai-go/v3"
+import (
+	"fmt"
+	"strings"
 
-func defineTools() []openai.ChatCompletionToolUnionParam {
-	return []openai.ChatCompletionToolUnionParam{
+	"browser-agent/internal/keyboard"
+)
+
+// ToolSpec — описание инструмента, независимое от провайдера.
+// Parameters — JSON Schema аргументов; каждый адаптер заворачивает его в свой формат.
+type ToolSpec struct {
+	Name        string
+	Description string
+	Parameters  map[string]any
+}
+
+// defineTools — инструменты агента. resultSchema (JSON Schema результата задачи,
+// может быть nil) становится обязательным аргументом result у submit_task_result.
+func defineTools(resultSchema map[string]any) []ToolSpec {
+	return []ToolSpec{
 		// 1. CLICK - Клик по элементу
-		openai.ChatCompletionFunctionTool(openai.FunctionDefinitionParam{
+		{
 			Name:        "click",
-			Description: openai.String("Кликнуть по элементу (ссылка, кнопка, чекбокс)."),
-			Parameters: openai.FunctionParameters{
+			Description: "Кликнуть по элементу (ссылка, кнопка, чекбокс).",
+			Parameters: map[string]any{
 				"type": "object",
 				"properties": map[string]any{
 					"id": map[string]any{
@@ -18,13 +33,13 @@ func defineTools() []openai.ChatCompletionToolUnionParam {
 				},
 				"required": []string{"id"},
 			},
-		}),
+		},
 
 		// 2. TYPE - Ввод текста
-		openai.ChatCompletionFunctionTool(openai.FunctionDefinitionParam{
+		{
 			Name:        "type",
-			Description: openai.String("Ввести текст в поле ввода."),
-			Parameters: openai.FunctionParameters{
+			Description: "Ввести текст в поле ввода.",
+			Parameters: map[string]any{
 				"type": "object",
 				"properties": map[string]any{
 					"id": map[string]any{
@@ -38,46 +53,38 @@ func defineTools() []openai.ChatCompletionToolUnionParam {
 				},
 				"required": []string{"id", "text"},
 			},
-		}),
+		},
 		// 8. DONE - Завершение задачи
-		openai.ChatCompletionFunctionTool(openai.FunctionDefinitionParam{
+		{
 			Name:        "submit_task_result", // <--- Новое имя
-			Description: openai.String("Вызови эту функцию, чтобы сдать финальный отчет и завершить работу агента."),
-			Parameters: openai.FunctionParameters{
-				"type": "object",
-				"properties": map[string]any{
-					"final_report": map[string]any{ // <--- Новое поле
-						"type":        "string",
-						"description": "Подробный результат выполнения задачи для пользователя.",
-					},
-				},
-				"required": []string{"final_report"},
-			},
-		}),
+			Description: "Вызови эту функцию, чтобы сдать финальный отчет и завершить работу агента.",
+			Parameters:  submitParameters(resultSchema),
+		},
 
-		// 3. PRESS - Нажатие спецклавиш
-		openai.ChatCompletionFunctionTool(openai.FunctionDefinitionParam{
+		// 3. PRESS - Нажатие клавиш и сочетаний
+		{
 			Name:        "press",
-			Description: openai.String("Нажать специальную клавишу (например, Enter после ввода)."),
-			Parameters: openai.FunctionParameters{
+			Description: "Нажать клавишу, сочетание клавиш или несколько по очереди (Enter после ввода, Control+A, Shift+Tab, Escape для закрытия окна).",
+			Parameters: map[string]any{
 				"type": "object",
 				"properties": map[string]any{
+					// Имена берутся из того же пакета, которым PressKey их разбирает
 					"key": map[string]any{
-						"type":        "string",
-						"description": "Название клавиши.",
-						// Ограничиваем список, чтобы модель не придумывала свои названия
-						"enum": []string{"Enter", "Backspace", "Escape", "Tab", "Delete", "ArrowDown", "ArrowUp"},
+						"type": "string",
+						"description": fmt.Sprintf("Клавиша: %s или один символ (A, 1, /). Сочетание — через +: модификаторы %s, затем клавиша. "+
+							"Повтор — *N, несколько нажатий — через пробел. Примеры: %s.",
+							strings.Join(keyboard.Names(), ", "), strings.Join(keyboard.ModifierNames(), ", "), strings.Join(keyboard.Examples, ", ")),
 					},
 				},
 				"required": []string{"key"},
 			},
-		}),
+		},
 
 		// 4. SCROLL - Прокрутка страницы
-		openai.ChatCompletionFunctionTool(openai.FunctionDefinitionParam{
+		{
 			Name:        "scroll",
-			Description: openai.String("Прокрутить страницу, если нужный элемент не виден."),
-			Parameters: openai.FunctionParameters{
+			Description: "Прокрутить страницу, если нужный элемент не виден.",
+			Parameters: map[string]any{
 				"type": "object",
 				"properties": map[string]any{
 					"direction": map[string]any{
@@ -88,13 +95,13 @@ func defineTools() []openai.ChatCompletionToolUnionParam {
 				},
 				"required": []string{"direction"},
 			},
-		}),
+		},
 
 		// 5. NAVIGATE - Переход по URL
-		openai.ChatCompletionFunctionTool(openai.FunctionDefinitionParam{
+		{
 			Name:        "navigate",
-			Description: openai.String("Перейти на конкретный URL. Использовать только для начала работы или если ссылка не кликабельна."),
-			Parameters: openai.FunctionParameters{
+			Description: "Перейти на конкретный URL. Использовать только для начала работы или если ссылка не кликабельна.",
+			Parameters: map[string]any{
 				"type": "object",
 				"properties": map[string]any{
 					"url": map[string]any{
@@ -104,13 +111,49 @@ func defineTools() []openai.ChatCompletionToolUnionParam {
 				},
 				"required": []string{"url"},
 			},
-		}),
+		},
+
+		// 6. GO_BACK - Кнопка "Назад"
+		{
+			Name:        "go_back",
+			Description: "Вернуться на предыдущую страницу (кнопка \"Назад\" браузера).",
+			Parameters: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{},
+			},
+		},
+
+		// 9. READ_TEXT - Чтение длинного текста
+		{
+			Name:        "read_text",
+			Description: "Прочитать полный текст элемента (письмо, статья, описание товара), если в DOM он обрезан. Текст вернется в результате действия.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"id": map[string]any{
+						"type":        "integer",
+						"description": "ID элемента, текст которого нужно прочитать.",
+					},
+				},
+				"required": []string{"id"},
+			},
+		},
+
+		// 10. CLOSE_TAB - Закрыть вкладку
+		{
+			Name:        "close_tab",
+			Description: "Закрыть текущую вкладку и вернуться к предыдущей (например, после того как ссылка открылась в новой вкладке).",
+			Parameters: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{},
+			},
+		},
 
 		// 7. MEMORIZE - Память агента
-		openai.ChatCompletionFunctionTool(openai.FunctionDefinitionParam{
+		{
 			Name:        "memorize",
-			Description: openai.String("Сохранить важную информацию в память (например, содержимое письма или статус задачи)."),
-			Parameters: openai.FunctionParameters{
+			Description: "Сохранить важную информацию в память (например, содержимое письма или статус задачи).",
+			Parameters: map[string]any{
 				"type": "object",
 				"properties": map[string]any{
 					"info": map[string]any{
@@ -120,6 +163,203 @@ func defineTools() []openai.ChatCompletionToolUnionParam {
 				},
 				"required": []string{"info"},
 			},
-		}),
+		},
+
+		// 11. EXTRACT_TABLE - Таблица в строки
+		{
+			Name:        "extract_table",
+			Description: "Извлечь HTML-таблицу в JSON-строки (колонки по заголовкам). Строки вернутся в результате действия и попадут в итог задачи.",
+			Parameters:  extractParameters("ID любого элемента внутри таблицы (ссылки, кнопки в строке). Без ID — самая длинная таблица страницы."),
+		},
+
+		// 12. EXTRACT_LIST - Повторяющиеся блоки в строки
+		{
+			Name:        "extract_list",
+			Description: "Извлечь повторяющиеся блоки (пункты списка, карточки товаров, результаты поиска) в JSON-строки с текстом и ссылкой каждого блока.",
+			Parameters:  extractParameters("ID элемента внутри одного из блоков (например, ссылка в карточке). Без ID — самая большая группа блоков на странице."),
+		},
+
+		// 13. SELECT_OPTION - Выпадающий список
+		{
+			Name:        "select_option",
+			Description: "Выбрать вариант в выпадающем списке [CHOOSE] по его видимому тексту. Для самописных меню (div вместо <select>) кликай по пунктам.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"id": map[string]any{
+						"type":        "integer",
+						"description": "ID элемента [CHOOSE].",
+					},
+					"option": map[string]any{
+						"type":        "string",
+						"description": "Текст варианта, как он показан в {…}; достаточно однозначной части.",
+					},
+				},
+				"required": []string{"id", "option"},
+			},
+		},
+
+		// 14. SET_VALUE - Дата, время, ползунок
+		{
+			Name:        "set_value",
+			Description: "Задать значение полю [DATE] (дата/время) или ползунку [RANGE], куда нельзя ввести текст с клавиатуры.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"id": map[string]any{
+						"type":        "integer",
+						"description": "ID элемента [DATE] или [RANGE].",
+					},
+					"value": map[string]any{
+						"type":        "string",
+						"description": "Значение в формате из скобок в DOM: 2025-03-14 для даты, 18:30 для времени, число для ползунка.",
+					},
+				},
+				"required": []string{"id", "value"},
+			},
+		},
+
+		// 15. UPLOAD_FILE - Прикрепить файл
+		{
+			Name:        "upload_file",
+			Description: "Прикрепить файл к полю [FILE] (резюме, фото, документ). Доступны только файлы из каталога загрузок, указанного пользователем.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"id": map[string]any{
+						"type":        "integer",
+						"description": "ID элемента [FILE].",
+					},
+					"file": map[string]any{
+						"type":        "string",
+						"description": "Имя файла в каталоге загрузок (например, cv.pdf).",
+					},
+				},
+				"required": []string{"id", "file"},
+			},
+		},
+
+		// 16-18. Другие действия мышью — те же аргументы, что у click
+		{
+			Name:        "hover",
+			Description: "Навести курсор на элемент, чтобы раскрыть меню или подсказку, которые появляются только под мышью.",
+			Parameters:  idParameters("ID элемента, на который навести курсор."),
+		},
+		{
+			Name:        "double_click",
+			Description: "Двойной клик по элементу (открыть файл, начать редактирование ячейки).",
+			Parameters:  idParameters("ID элемента из DOM (число в квадратных скобках)."),
+		},
+		{
+			Name:        "right_click",
+			Description: "Клик правой кнопкой мыши — открыть контекстное меню элемента.",
+			Parameters:  idParameters("ID элемента из DOM (число в квадратных скобках)."),
+		},
+
+		// 19. DRAG - Перетаскивание
+		{
+			Name:        "drag",
+			Description: "Перетащить элемент на другой (карточка в колонку канбан-доски, файл в папку, ползунок к отметке).",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"from_id": map[string]any{
+						"type":        "integer",
+						"description": "ID элемента, который перетаскиваем ([DRAG] или [CLICK]).",
+					},
+					"to_id": map[string]any{
+						"type":        "integer",
+						"description": "ID элемента, на который бросить (любой элемент внутри нужной колонки или области).",
+					},
+				},
+				"required": []string{"from_id", "to_id"},
+			},
+		},
+
+		// 20. WAIT_FOR - Дождаться подгрузки
+		{
+			Name:        "wait_for",
+			Description: "Дождаться, пока страница догрузится: появится или пропадет текст (результаты поиска, \"Загрузка...\"), затихнет сеть или DOM. Нужен, если данные подгружаются после действия не сразу.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"condition": map[string]any{
+						"type":        "string",
+						"description": "text — появился текст, text_gone — текст пропал, network_idle — нет запросов, dom_quiet — страница перестала меняться, navigation — переход завершен.",
+						"enum":        []string{"text", "text_gone", "network_idle", "dom_quiet", "navigation"},
+					},
+					"text": map[string]any{
+						"type":        "string",
+						"description": "Текст для text и text_gone (без учета регистра).",
+					},
+					"timeout": map[string]any{
+						"type":        "integer",
+						"description": "Сколько ждать, секунд (по умолчанию 10, максимум 30).",
+					},
+				},
+				"required": []string{"condition"},
+			},
+		},
+	}
+}
+
+// submitParameters — аргументы submit_task_result: текстовый отчет и, если у задачи
+// есть схема, структурированный result строго по ней
+func submitParameters(resultSchema map[string]any) map[string]any {
+	properties := map[string]any{
+		"final_report": map[string]any{ // <--- Новое поле
+			"type":        "string",
+			"description": "Подробный результат выполнения задачи для пользователя.",
+		},
+	}
+	required := []string{"final_report"}
+
+	if resultSchema != nil {
+		result := make(map[string]any, len(resultSchema)+1)
+		for k, v := range resultSchema {
+			result[k] = v
+		}
+		if _, ok := result["description"]; !ok {
+			result["description"] = "Данные результата строго по JSON Schema задачи."
+		}
+		properties["result"] = result
+		required = append(required, "result")
+	}
+
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// idParameters — единственный аргумент id (действия мышью над одним элементом)
+func idParameters(description string) map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id": map[string]any{
+				"type":        "integer",
+				"description": description,
+			},
+		},
+		"required": []string{"id"},
+	}
+}
+
+// extractParameters — общие аргументы extract_table и extract_list
+func extractParameters(idDescription string) map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id": map[string]any{
+				"type":        "integer",
+				"description": idDescription,
+			},
+			"max_rows": map[string]any{
+				"type":        "integer",
+				"description": "Сколько строк вернуть (по умолчанию 50, максимум 200).",
+			},
+		},
 	}
 }
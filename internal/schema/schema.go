@@ -0,0 +1,276 @@
+// Package schema — минимальная проверка JSON по JSON Schema для структурированного
+// результата задачи. Поддерживается подмножество, которого хватает для описания
+// данных со страницы: type, properties, required, additionalProperties (false),
+// items, enum, minItems/maxItems, minLength/maxLength, minimum/maximum.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema — JSON Schema в том виде, в каком ее отдает json.Unmarshal
+type Schema = map[string]any
+
+// maxErrors — сколько расхождений перечислять: модели хватит первых, чтобы исправиться
+const maxErrors = 5
+
+var knownTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// supported — ключевые слова, которые Validate проверяет; описательные
+// (title, description, $schema, examples, default) допустимы и ни на что не влияют
+var supported = map[string]bool{
+	"type": true, "properties": true, "required": true, "additionalProperties": true,
+	"items": true, "enum": true, "minItems": true, "maxItems": true,
+	"minLength": true, "maxLength": true, "minimum": true, "maximum": true,
+	"title": true, "description": true, "$schema": true, "examples": true, "default": true,
+}
+
+// Parse разбирает схему из JSON и проверяет, что Validate ее понимает
+func Parse(data []byte) (Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("schema is not a JSON object: %w", err)
+	}
+	if err := Check(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Load читает схему из файла
+func Load(path string) (Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Check отклоняет схему с неподдерживаемыми ключевыми словами ($ref, oneOf,
+// pattern...): молча пропускать их — значит принимать данные, которые схеме
+// на самом деле не соответствуют
+func Check(s Schema) error {
+	return check(s, "$")
+}
+
+func check(s Schema, path string) error {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !supported[k] {
+			return fmt.Errorf("%s: unsupported keyword %q", path, k)
+		}
+	}
+
+	for _, t := range types(s) {
+		if !knownTypes[t] {
+			return fmt.Errorf("%s: unknown type %q", path, t)
+		}
+	}
+	if props, ok := s["properties"]; ok {
+		m, ok := props.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: properties must be an object", path)
+		}
+		for name, sub := range m {
+			subSchema, ok := sub.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s.%s: schema must be an object", path, name)
+			}
+			if err := check(subSchema, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	if items, ok := s["items"]; ok {
+		subSchema, ok := items.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: items must be a single schema object", path)
+		}
+		if err := check(subSchema, path+"[]"); err != nil {
+			return err
+		}
+	}
+	if ap, ok := s["additionalProperties"]; ok {
+		if _, ok := ap.(bool); !ok {
+			return fmt.Errorf("%s: only boolean additionalProperties is supported", path)
+		}
+	}
+	return nil
+}
+
+// Validate проверяет value (результат json.Unmarshal в any) по схеме.
+// Ошибка перечисляет первые расхождения с путями вида $.items[2].price.
+func Validate(s Schema, value any) error {
+	var errs []string
+	validate(s, value, "$", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	if len(errs) > maxErrors {
+		errs = append(errs[:maxErrors], fmt.Sprintf("... and %d more", len(errs)-maxErrors))
+	}
+	return fmt.Errorf("%s", strings.Join(errs, "; "))
+}
+
+func validate(s Schema, value any, path string, errs *[]string) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if ts := types(s); len(ts) > 0 {
+		matched := false
+		for _, t := range ts {
+			if hasType(value, t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("expected %s, got %s", strings.Join(ts, " or "), typeOf(value))
+			return
+		}
+	}
+
+	if enum, ok := s["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			allowed, _ := json.Marshal(enum)
+			fail("must be one of %s", allowed)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		props, _ := s["properties"].(map[string]any)
+		if required, ok := s["required"].([]any); ok {
+			for _, r := range required {
+				name, _ := r.(string)
+				if _, ok := v[name]; !ok {
+					fail("missing required property %q", name)
+				}
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			sub, ok := props[name].(map[string]any)
+			if !ok {
+				if s["additionalProperties"] == false {
+					fail("unexpected property %q", name)
+				}
+				continue
+			}
+			validate(sub, v[name], path+"."+name, errs)
+		}
+
+	case []any:
+		if n, ok := number(s["minItems"]); ok && float64(len(v)) < n {
+			fail("expected at least %v items, got %d", n, len(v))
+		}
+		if n, ok := number(s["maxItems"]); ok && float64(len(v)) > n {
+			fail("expected at most %v items, got %d", n, len(v))
+		}
+		if items, ok := s["items"].(map[string]any); ok {
+			for i, item := range v {
+				validate(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if n, ok := number(s["minLength"]); ok && length < n {
+			fail("expected at least %v characters", n)
+		}
+		if n, ok := number(s["maxLength"]); ok && length > n {
+			fail("expected at most %v characters", n)
+		}
+
+	case float64:
+		if n, ok := number(s["minimum"]); ok && v < n {
+			fail("must be >= %v", n)
+		}
+		if n, ok := number(s["maximum"]); ok && v > n {
+			fail("must be <= %v", n)
+		}
+	}
+}
+
+// types — значение type строкой или списком строк
+func types(s Schema) []string {
+	switch t := s["type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var out []string
+		for _, x := range t {
+			if str, ok := x.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func hasType(value any, t string) bool {
+	switch t {
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return typeOf(value) == t
+	}
+}
+
+func typeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func number(v any) (float64, bool) {
+	f, ok := v.(float64)
+	return f, ok
+}
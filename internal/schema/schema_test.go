@@ -0,0 +1,98 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const productsSchema = `{
+	"type": "object",
+	"properties": {
+		"products": {
+			"type": "array",
+			"minItems": 1,
+			"items": {
+				"type": "object",
+				"properties": {
+					"name":     {"type": "string", "minLength": 1},
+					"price":    {"type": "number", "minimum": 0},
+					"currency": {"enum": ["RUB", "USD"]},
+					"stock":    {"type": ["integer", "null"]}
+				},
+				"required": ["name", "price"],
+				"additionalProperties": false
+			}
+		}
+	},
+	"required": ["products"]
+}`
+
+func decode(t *testing.T, s string) any {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	s, err := Parse([]byte(productsSchema))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		value string
+		want  []string // Подстроки ошибки; пусто — данные валидны
+	}{
+		{"valid", `{"products": [{"name": "Слон", "price": 100, "currency": "RUB", "stock": 3}, {"name": "Кот", "price": 9.5, "stock": null}]}`, nil},
+		{"not an object", `[1, 2]`, []string{"$: expected object, got array"}},
+		{"missing required", `{}`, []string{`$: missing required property "products"`}},
+		{"empty array", `{"products": []}`, []string{"$.products: expected at least 1 items"}},
+		{"wrong item types", `{"products": [{"name": "Слон", "price": "100 ₽"}]}`, []string{"$.products[0].price: expected number, got string"}},
+		{"not integer", `{"products": [{"name": "Слон", "price": 1, "stock": 1.5}]}`, []string{"$.products[0].stock: expected integer or null, got number"}},
+		{"enum and extra", `{"products": [{"name": "", "price": -1, "currency": "EUR", "color": "серый"}]}`, []string{
+			`$.products[0]: unexpected property "color"`,
+			`$.products[0].currency: must be one of ["RUB","USD"]`,
+			"$.products[0].name: expected at least 1 characters",
+			"$.products[0].price: must be >= 0",
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(s, decode(t, tc.value))
+			if len(tc.want) == 0 {
+				if err != nil {
+					t.Fatalf("Expected valid, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected error with %q", tc.want)
+			}
+			for _, w := range tc.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("Error %q does not contain %q", err, w)
+				}
+			}
+		})
+	}
+}
+
+func TestParse_RejectsUnsupportedSchemas(t *testing.T) {
+	for _, bad := range []string{
+		`[]`,
+		`{"type": "object", "properties": {"a": {"$ref": "#/defs/a"}}}`,
+		`{"type": "text"}`,
+		`{"type": "array", "items": [{"type": "string"}]}`,
+		`{"type": "object", "additionalProperties": {"type": "string"}}`,
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Errorf("Expected error for %s", bad)
+		}
+	}
+}
@@ -0,0 +1,433 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"browser-agent/internal/agent"
+	"browser-agent/internal/schema"
+)
+
+const (
+	// queueSize — сколько задач может ждать браузер, прежде чем API начнет отвечать 503
+	queueSize = 100
+	// eventBuffer — буфер подписки на шину событий оркестратора
+	eventBuffer = 1024
+
+	// Завершенные задачи держат в памяти шаги, события и отчет — сервер помнит
+	// не больше keepFinished последних и не дольше finishedTTL
+	keepFinished = 100
+	finishedTTL  = time.Hour
+)
+
+// Server отдает агента как HTTP/JSON сервис.
+// Браузер один, поэтому задачи выполняются строго по очереди одним воркером.
+type Server struct {
+	orchestrator *agent.Orchestrator
+
+	// KeepFinished и FinishedTTL — лимиты на завершенные задачи; после них
+	// GET /tasks/{id} отвечает 404. Очереди и выполняемой задачи не касаются.
+	KeepFinished int
+	FinishedTTL  time.Duration
+
+	mu    sync.RWMutex
+	tasks map[string]*Task
+	queue chan *Task
+}
+
+func New(o *agent.Orchestrator) *Server {
+	return &Server{
+		orchestrator: o,
+		KeepFinished: keepFinished,
+		FinishedTTL:  finishedTTL,
+		tasks:        make(map[string]*Task),
+		queue:        make(chan *Task, queueSize),
+	}
+}
+
+// Run — воркер очереди, блокируется до отмены ctx
+func (s *Server) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-s.queue:
+			s.runTask(ctx, t)
+		}
+	}
+}
+
+func (s *Server) runTask(ctx context.Context, t *Task) {
+	taskCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if t.Timeout > 0 {
+		taskCtx, cancel = context.WithTimeout(taskCtx, t.Timeout)
+		defer cancel()
+	}
+
+	// Задачу могли отменить, пока она стояла в очереди
+	if !t.start(cancel) {
+		return
+	}
+
+	log.Printf("🏁 [API] Задача %s: '%s'", t.ID, t.Prompt)
+
+	// Воркер один, поэтому всё, что пришло в шину за время RunTask, — события этой задачи
+	events, unsubscribe := s.orchestrator.Events.Subscribe(eventBuffer)
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		for e := range events {
+			t.addEvent(e)
+		}
+	}()
+
+	result := s.orchestrator.RunTaskWithSchema(taskCtx, t.Prompt, t.Schema)
+
+	// Дочитываем хвост событий (включая task_finished) до смены статуса,
+	// чтобы стримы не закрылись раньше времени
+	unsubscribe()
+	<-forwarded
+
+	t.finish(result)
+	log.Printf("✨ [API] Задача %s завершена: %s", t.ID, result.Status)
+	s.evict()
+}
+
+// evict забывает завершенные задачи старше FinishedTTL и сверх KeepFinished
+// последних. Открытые стримы держат свою задачу и дочитывают ее до конца.
+func (s *Server) evict() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type done struct {
+		id string
+		at time.Time
+	}
+	var finished []done
+	for id, t := range s.tasks {
+		at, ok := t.finishedAt()
+		if !ok {
+			continue
+		}
+		if s.FinishedTTL > 0 && time.Since(at) > s.FinishedTTL {
+			delete(s.tasks, id)
+			continue
+		}
+		finished = append(finished, done{id, at})
+	}
+
+	if len(finished) <= s.KeepFinished {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].at.After(finished[j].at) })
+	for _, d := range finished[s.KeepFinished:] {
+		delete(s.tasks, d.id)
+	}
+}
+
+// Handler возвращает роутер API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /tasks", s.handleSubmit)
+	mux.HandleFunc("GET /tasks", s.handleList)
+	mux.HandleFunc("GET /tasks/{id}", s.handleStatus)
+	mux.HandleFunc("GET /tasks/{id}/steps", s.handleSteps)
+	mux.HandleFunc("GET /tasks/{id}/events", s.handleTaskEvents)
+	mux.HandleFunc("GET /events", s.handleEvents)
+	mux.HandleFunc("GET /memory", s.handleMemory)
+	mux.HandleFunc("DELETE /memory", s.handleClearMemory)
+	mux.HandleFunc("GET /tasks/{id}/report", s.handleReport)
+	mux.HandleFunc("POST /tasks/{id}/cancel", s.handleCancel)
+	return mux
+}
+
+type submitRequest struct {
+	Task    string          `json:"task"`
+	Timeout string          `json:"timeout,omitempty"` // Go duration: "90s", "5m"
+	Schema  json.RawMessage `json:"schema,omitempty"`  // JSON Schema результата (output в отчете)
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var req submitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON: %v", err))
+		return
+	}
+	if req.Task == "" {
+		writeError(w, http.StatusBadRequest, "'task' is required")
+		return
+	}
+
+	var timeout time.Duration
+	if req.Timeout != "" {
+		d, err := time.ParseDuration(req.Timeout)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid timeout: %v", err))
+			return
+		}
+		timeout = d
+	}
+
+	var resultSchema schema.Schema
+	if len(req.Schema) > 0 {
+		parsed, err := schema.Parse(req.Schema)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid schema: %v", err))
+			return
+		}
+		resultSchema = parsed
+	}
+
+	t := newTask(newTaskID(), req.Task, timeout)
+	t.Schema = resultSchema
+	s.evict()
+
+	s.mu.Lock()
+	s.tasks[t.ID] = t
+	s.mu.Unlock()
+
+	select {
+	case s.queue <- t:
+	default:
+		s.mu.Lock()
+		delete(s.tasks, t.ID)
+		s.mu.Unlock()
+		writeError(w, http.StatusServiceUnavailable, "task queue is full")
+		return
+	}
+
+	w.Header().Set("Location", "/tasks/"+t.ID)
+	writeJSON(w, http.StatusAccepted, t.View())
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	views := make([]TaskView, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		views = append(views, t.View())
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(views, func(i, j int) bool {
+		return views[i].CreatedAt.Before(views[j].CreatedAt)
+	})
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, t.View())
+}
+
+// handleSteps стримит шаги задачи в NDJSON (по одному ActionRecord на строку)
+// и закрывает поток, когда задача завершается.
+func (s *Server) handleSteps(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	sent := 0
+
+	for {
+		steps, done, changed := t.StepsSince(sent)
+		for _, step := range steps {
+			if err := encoder.Encode(step); err != nil {
+				return
+			}
+		}
+		sent += len(steps)
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if done {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleTaskEvents отдает события задачи через Server-Sent Events:
+// сначала уже накопленные, затем новые; поток закрывается после task_finished.
+func (s *Server) handleTaskEvents(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	flusher, ok := startSSE(w)
+	if !ok {
+		return
+	}
+	sent := 0
+
+	for {
+		events, done, changed := t.EventsSince(sent)
+		for _, e := range events {
+			if err := writeSSE(w, e); err != nil {
+				return
+			}
+		}
+		sent += len(events)
+		flusher.Flush()
+
+		if done {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleEvents — живой SSE-поток всех событий оркестратора (без истории)
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := startSSE(w)
+	if !ok {
+		return
+	}
+
+	events, unsubscribe := s.orchestrator.Events.Subscribe(eventBuffer)
+	defer unsubscribe()
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeSSE(w, e); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	result := t.Result()
+	if result == nil {
+		writeError(w, http.StatusConflict, fmt.Sprintf("task is %s", t.View().Status))
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	if !t.Cancel() {
+		writeError(w, http.StatusConflict, "task is already finished")
+		return
+	}
+	view := t.View()
+	s.evict()
+	writeJSON(w, http.StatusAccepted, view)
+}
+
+type memoryView struct {
+	Persistent bool     `json:"persistent"`
+	Facts      []string `json:"facts"`
+}
+
+func (s *Server) handleMemory(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, memoryView{
+		Persistent: s.orchestrator.PersistMemory,
+		Facts:      s.orchestrator.Memory.Facts(),
+	})
+}
+
+func (s *Server) handleClearMemory(w http.ResponseWriter, r *http.Request) {
+	s.orchestrator.Memory.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) (*Task, bool) {
+	id := r.PathValue("id")
+
+	s.mu.RLock()
+	t, ok := s.tasks[id]
+	s.mu.RUnlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("task %s not found", id))
+	}
+	return t, ok
+}
+
+func newTaskID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func startSSE(w http.ResponseWriter) (http.Flusher, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return nil, false
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return flusher, true
+}
+
+// writeSSE пишет одно событие в формате "event: <type>\ndata: <json>\n\n"
+func writeSSE(w http.ResponseWriter, e agent.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
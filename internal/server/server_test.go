@@ -0,0 +1,380 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"browser-agent/internal/agent"
+	"browser-agent/internal/agent/agenttest"
+	"browser-agent/internal/entity"
+)
+
+func testPages() map[string]*agenttest.Page {
+	return map[string]*agenttest.Page{
+		"about:blank": {Title: "New Tab", DOM: "Page is empty"},
+		"https://shop.test": {
+			Title: "Магазин",
+			DOM:   "[5] <link> [NAVIGATE] Слон плюшевый\n",
+			Texts: map[int]string{5: "Слон плюшевый, 100 монет"},
+		},
+	}
+}
+
+func call(name string, args map[string]interface{}) agenttest.ScriptCall {
+	return agenttest.ScriptCall{Name: name, Args: args}
+}
+
+// submit — шаг сценария, который сразу завершает задачу
+func submit(report string) agenttest.ScriptStep {
+	return agenttest.ScriptStep{Calls: []agenttest.ScriptCall{
+		call("submit_task_result", map[string]interface{}{"final_report": report}),
+	}}
+}
+
+// newTestServer поднимает API над оркестратором с фейковыми браузером и моделью.
+// Сценарий общий для всех задач сервера: каждая задача проигрывает свои шаги по очереди.
+// configure настраивает сервер до запуска воркера.
+func newTestServer(t *testing.T, configure func(*Server), steps ...agenttest.ScriptStep) (*Server, *httptest.Server) {
+	t.Helper()
+	o := agent.New(agenttest.NewBrowser("about:blank", testPages()), agenttest.NewScriptedBrain(steps...))
+	o.Out = io.Discard
+
+	s := New(o)
+	if configure != nil {
+		configure(s)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go s.Run(ctx)
+
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(func() {
+		cancel()
+		ts.Close()
+	})
+	return s, ts
+}
+
+func do(t *testing.T, method, url, body string) (int, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s %s: read body: %v", method, url, err)
+	}
+	return resp.StatusCode, data
+}
+
+func submitTask(t *testing.T, ts *httptest.Server, body string) TaskView {
+	t.Helper()
+	code, data := do(t, http.MethodPost, ts.URL+"/tasks", body)
+	if code != http.StatusAccepted {
+		t.Fatalf("POST /tasks: expected 202, got %d: %s", code, data)
+	}
+	var view TaskView
+	if err := json.Unmarshal(data, &view); err != nil {
+		t.Fatalf("POST /tasks: %v", err)
+	}
+	return view
+}
+
+func getTask(t *testing.T, ts *httptest.Server, id string) TaskView {
+	t.Helper()
+	code, data := do(t, http.MethodGet, ts.URL+"/tasks/"+id, "")
+	if code != http.StatusOK {
+		t.Fatalf("GET /tasks/%s: expected 200, got %d: %s", id, code, data)
+	}
+	var view TaskView
+	if err := json.Unmarshal(data, &view); err != nil {
+		t.Fatalf("GET /tasks/%s: %v", id, err)
+	}
+	return view
+}
+
+// waitStatus опрашивает задачу, пока она не перейдет в status
+func waitStatus(t *testing.T, ts *httptest.Server, id string, status entity.TaskStatus) TaskView {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		view := getTask(t, ts, id)
+		if view.Status == status {
+			return view
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Task %s: expected %s, still %s", id, status, view.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// sseEvents читает SSE-поток до конца и возвращает типы событий по порядку
+func sseEvents(t *testing.T, url string) []string {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("GET %s: unexpected Content-Type %q", url, ct)
+	}
+
+	var types []string
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if event, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			types = append(types, event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	return types
+}
+
+func TestSubmit_StatusStepsAndEvents(t *testing.T) {
+	_, ts := newTestServer(t, nil,
+		agenttest.ScriptStep{Calls: []agenttest.ScriptCall{
+			call("navigate", map[string]interface{}{"url": "https://shop.test"}),
+		}},
+		agenttest.ScriptStep{
+			When: &agenttest.Condition{DOMContains: "Слон"},
+			Calls: []agenttest.ScriptCall{
+				call("read_text", map[string]interface{}{"id": 5}),
+				call("submit_task_result", map[string]interface{}{"final_report": "Слон стоит 100 монет"}),
+			},
+		},
+	)
+
+	code, data := do(t, http.MethodPost, ts.URL+"/tasks", `{"task": "Узнай цену слона", "timeout": "1m"}`)
+	if code != http.StatusAccepted {
+		t.Fatalf("POST /tasks: expected 202, got %d: %s", code, data)
+	}
+	var submitted TaskView
+	if err := json.Unmarshal(data, &submitted); err != nil {
+		t.Fatalf("POST /tasks: %v", err)
+	}
+	if submitted.ID == "" || submitted.Task != "Узнай цену слона" {
+		t.Fatalf("Unexpected task view: %+v", submitted)
+	}
+
+	// NDJSON-поток шагов закрывается сам, когда задача завершается
+	resp, err := http.Get(ts.URL + "/tasks/" + submitted.ID + "/steps")
+	if err != nil {
+		t.Fatalf("GET steps: %v", err)
+	}
+	var actions []string
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var record entity.ActionRecord
+		if err := decoder.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("GET steps: %v", err)
+		}
+		actions = append(actions, record.Action)
+	}
+	resp.Body.Close()
+	if want := "navigate,read_text,submit_task_result"; strings.Join(actions, ",") != want {
+		t.Errorf("Steps stream: got %v, want %s", actions, want)
+	}
+
+	view := getTask(t, ts, submitted.ID)
+	if view.Status != entity.TaskCompleted {
+		t.Fatalf("Expected completed, got %s (%s)", view.Status, view.Error)
+	}
+	// Три действия за два шага оркестратора
+	if view.Steps != 2 {
+		t.Errorf("Expected 2 steps, got %d", view.Steps)
+	}
+	if view.FinalURL != "https://shop.test" || view.StartedAt == nil || view.FinishedAt == nil {
+		t.Errorf("Unexpected task view: %+v", view)
+	}
+
+	// SSE завершенной задачи: вся история и конец потока после task_finished
+	events := sseEvents(t, ts.URL+"/tasks/"+submitted.ID+"/events")
+	if len(events) == 0 || events[0] != string(agent.EventTaskStarted) || events[len(events)-1] != string(agent.EventTaskFinished) {
+		t.Errorf("Unexpected events: %v", events)
+	}
+
+	code, data = do(t, http.MethodGet, ts.URL+"/tasks/"+submitted.ID+"/report", "")
+	if code != http.StatusOK {
+		t.Fatalf("GET report: expected 200, got %d: %s", code, data)
+	}
+	var result entity.TaskResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("GET report: %v", err)
+	}
+	if result.FinalReport != "Слон стоит 100 монет" {
+		t.Errorf("Unexpected final report: %q", result.FinalReport)
+	}
+
+	code, data = do(t, http.MethodGet, ts.URL+"/tasks", "")
+	if code != http.StatusOK || !strings.Contains(string(data), submitted.ID) {
+		t.Errorf("GET /tasks: got %d: %s", code, data)
+	}
+}
+
+func TestCancel_QueuedAndRunning(t *testing.T) {
+	// Первая задача ждет страницу, которая не появится, — выполняется, пока ее не отменят
+	_, ts := newTestServer(t, nil, agenttest.ScriptStep{
+		When:  &agenttest.Condition{DOMContains: "never"},
+		Calls: []agenttest.ScriptCall{call("submit_task_result", map[string]interface{}{"final_report": "-"})},
+	})
+
+	running := submitTask(t, ts, `{"task": "Долгая задача"}`)
+	waitStatus(t, ts, running.ID, entity.TaskRunning)
+	queued := submitTask(t, ts, `{"task": "Вторая задача"}`)
+
+	// Отчета еще нет
+	if code, data := do(t, http.MethodGet, ts.URL+"/tasks/"+running.ID+"/report", ""); code != http.StatusConflict {
+		t.Errorf("GET report of a running task: expected 409, got %d: %s", code, data)
+	}
+
+	// Задача в очереди отменяется сразу
+	code, data := do(t, http.MethodPost, ts.URL+"/tasks/"+queued.ID+"/cancel", "")
+	if code != http.StatusAccepted {
+		t.Fatalf("Cancel queued: expected 202, got %d: %s", code, data)
+	}
+	var view TaskView
+	if err := json.Unmarshal(data, &view); err != nil {
+		t.Fatalf("Cancel queued: %v", err)
+	}
+	if view.Status != entity.TaskCancelled {
+		t.Errorf("Cancel queued: expected cancelled, got %s", view.Status)
+	}
+
+	// Выполняемая — через контекст: поток событий дочитывается до task_finished
+	done := make(chan []string, 1)
+	go func() { done <- sseEvents(t, ts.URL+"/tasks/"+running.ID+"/events") }()
+
+	if code, data := do(t, http.MethodPost, ts.URL+"/tasks/"+running.ID+"/cancel", ""); code != http.StatusAccepted {
+		t.Fatalf("Cancel running: expected 202, got %d: %s", code, data)
+	}
+	view = waitStatus(t, ts, running.ID, entity.TaskCancelled)
+	if view.Error == "" {
+		t.Errorf("Cancelled task should explain why: %+v", view)
+	}
+
+	select {
+	case events := <-done:
+		if len(events) == 0 || events[len(events)-1] != string(agent.EventTaskFinished) {
+			t.Errorf("Events stream should end with task_finished, got %v", events)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Events stream was not closed after cancel")
+	}
+
+	// Отменить завершенную задачу нельзя
+	for _, id := range []string{running.ID, queued.ID} {
+		if code, data := do(t, http.MethodPost, ts.URL+"/tasks/"+id+"/cancel", ""); code != http.StatusConflict {
+			t.Errorf("Cancel finished %s: expected 409, got %d: %s", id, code, data)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	_, ts := newTestServer(t, nil)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"unknown task", http.MethodGet, "/tasks/nope", "", http.StatusNotFound},
+		{"unknown task steps", http.MethodGet, "/tasks/nope/steps", "", http.StatusNotFound},
+		{"unknown task events", http.MethodGet, "/tasks/nope/events", "", http.StatusNotFound},
+		{"unknown task report", http.MethodGet, "/tasks/nope/report", "", http.StatusNotFound},
+		{"unknown task cancel", http.MethodPost, "/tasks/nope/cancel", "", http.StatusNotFound},
+		{"invalid JSON", http.MethodPost, "/tasks", `{"task":`, http.StatusBadRequest},
+		{"missing task", http.MethodPost, "/tasks", `{"timeout": "1m"}`, http.StatusBadRequest},
+		{"invalid timeout", http.MethodPost, "/tasks", `{"task": "x", "timeout": "soon"}`, http.StatusBadRequest},
+		{"invalid schema", http.MethodPost, "/tasks", `{"task": "x", "schema": {"oneOf": []}}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, data := do(t, tt.method, ts.URL+tt.path, tt.body)
+			if code != tt.want {
+				t.Fatalf("Expected %d, got %d: %s", tt.want, code, data)
+			}
+			var body map[string]string
+			if err := json.Unmarshal(data, &body); err != nil || body["error"] == "" {
+				t.Errorf("Expected JSON error, got %s", data)
+			}
+		})
+	}
+}
+
+func TestMemory_GetAndDelete(t *testing.T) {
+	persist := func(s *Server) { s.orchestrator.PersistMemory = true }
+	_, ts := newTestServer(t, persist, agenttest.ScriptStep{Calls: []agenttest.ScriptCall{
+		call("memorize", map[string]interface{}{"info": "Слон стоит 100 монет"}),
+		call("submit_task_result", map[string]interface{}{"final_report": "Запомнил"}),
+	}})
+
+	task := submitTask(t, ts, `{"task": "Запомни цену"}`)
+	waitStatus(t, ts, task.ID, entity.TaskCompleted)
+
+	code, data := do(t, http.MethodGet, ts.URL+"/memory", "")
+	if code != http.StatusOK {
+		t.Fatalf("GET /memory: expected 200, got %d: %s", code, data)
+	}
+	var memory memoryView
+	if err := json.Unmarshal(data, &memory); err != nil {
+		t.Fatalf("GET /memory: %v", err)
+	}
+	if !memory.Persistent || len(memory.Facts) != 1 || memory.Facts[0] != "Слон стоит 100 монет" {
+		t.Errorf("Unexpected memory: %+v", memory)
+	}
+
+	if code, data := do(t, http.MethodDelete, ts.URL+"/memory", ""); code != http.StatusNoContent {
+		t.Fatalf("DELETE /memory: expected 204, got %d: %s", code, data)
+	}
+	_, data = do(t, http.MethodGet, ts.URL+"/memory", "")
+	if err := json.Unmarshal(data, &memory); err != nil {
+		t.Fatalf("GET /memory: %v", err)
+	}
+	if len(memory.Facts) != 0 {
+		t.Errorf("Memory should be empty after DELETE, got %v", memory.Facts)
+	}
+}
+
+func TestEvict_KeepsLastFinished(t *testing.T) {
+	keepOne := func(s *Server) { s.KeepFinished = 1 }
+	s, ts := newTestServer(t, keepOne, submit("первая"), submit("вторая"))
+
+	first := submitTask(t, ts, `{"task": "Первая"}`)
+	waitStatus(t, ts, first.ID, entity.TaskCompleted)
+	second := submitTask(t, ts, `{"task": "Вторая"}`)
+	waitStatus(t, ts, second.ID, entity.TaskCompleted)
+
+	if code, data := do(t, http.MethodGet, ts.URL+"/tasks/"+first.ID, ""); code != http.StatusNotFound {
+		t.Errorf("Oldest finished task should be evicted, got %d: %s", code, data)
+	}
+
+	// TTL забывает и последнюю
+	s.mu.Lock()
+	s.FinishedTTL = time.Nanosecond
+	s.mu.Unlock()
+	s.evict()
+	if code, data := do(t, http.MethodGet, ts.URL+"/tasks/"+second.ID, ""); code != http.StatusNotFound {
+		t.Errorf("Expired task should be evicted, got %d: %s", code, data)
+	}
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"browser-agent/internal/agent"
+	"browser-agent/internal/entity"
+)
+
+// Task — задача, поставленная через API, и всё, что о ней известно серверу
+type Task struct {
+	mu sync.Mutex
+
+	ID         string
+	Prompt     string
+	Timeout    time.Duration
+	Schema     map[string]any // JSON Schema результата задачи (nil — без схемы)
+	Status     entity.TaskStatus
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+
+	steps     []entity.ActionRecord // Выполненные действия (tool_executed), по одному на вызов инструмента
+	stepCount int                   // Шаги оркестратора (step_started): в одном шаге бывает несколько действий
+	events    []agent.Event
+	result    *entity.TaskResult
+
+	cancel  context.CancelFunc // Отменяет выполнение (nil, пока задача в очереди)
+	changed chan struct{}      // Закрывается и пересоздается при каждом новом шаге/смене статуса
+}
+
+// TaskView — то, что отдается наружу в GET /tasks/{id}
+type TaskView struct {
+	ID         string            `json:"id"`
+	Task       string            `json:"task"`
+	Status     entity.TaskStatus `json:"status"`
+	Steps      int               `json:"steps"` // Шаги оркестратора, а не число действий
+	CreatedAt  time.Time         `json:"created_at"`
+	StartedAt  *time.Time        `json:"started_at,omitempty"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+	FinalURL   string            `json:"final_url,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+func newTask(id, prompt string, timeout time.Duration) *Task {
+	return &Task{
+		ID:        id,
+		Prompt:    prompt,
+		Timeout:   timeout,
+		Status:    entity.TaskQueued,
+		CreatedAt: time.Now(),
+		steps:     []entity.ActionRecord{},
+		changed:   make(chan struct{}),
+	}
+}
+
+// View снимает консистентный снимок задачи
+func (t *Task) View() TaskView {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	v := TaskView{
+		ID:        t.ID,
+		Task:      t.Prompt,
+		Status:    t.Status,
+		Steps:     t.stepCount,
+		CreatedAt: t.CreatedAt,
+	}
+	if !t.StartedAt.IsZero() {
+		started := t.StartedAt
+		v.StartedAt = &started
+	}
+	if !t.FinishedAt.IsZero() {
+		finished := t.FinishedAt
+		v.FinishedAt = &finished
+	}
+	if t.result != nil {
+		v.FinalURL = t.result.FinalURL
+		v.Error = t.result.Error
+	}
+	return v
+}
+
+// Result возвращает итог задачи (nil, пока она не завершилась)
+func (t *Task) Result() *entity.TaskResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.result
+}
+
+// StepsSince возвращает шаги начиная с from, признак завершения задачи
+// и канал, который закроется при следующем изменении.
+func (t *Task) StepsSince(from int) ([]entity.ActionRecord, bool, <-chan struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var steps []entity.ActionRecord
+	if from < len(t.steps) {
+		steps = append(steps, t.steps[from:]...)
+	}
+	return steps, t.finished(), t.changed
+}
+
+// EventsSince — то же, что StepsSince, но для всех событий оркестратора
+func (t *Task) EventsSince(from int) ([]agent.Event, bool, <-chan struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var events []agent.Event
+	if from < len(t.events) {
+		events = append(events, t.events[from:]...)
+	}
+	return events, t.finished(), t.changed
+}
+
+// start переводит задачу в running. false — задачу успели отменить в очереди.
+func (t *Task) start(cancel context.CancelFunc) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.Status != entity.TaskQueued {
+		return false
+	}
+	t.Status = entity.TaskRunning
+	t.StartedAt = time.Now()
+	t.cancel = cancel
+	t.notify()
+	return true
+}
+
+func (t *Task) addEvent(e agent.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.events = append(t.events, e)
+	switch {
+	case e.Type == agent.EventStepStarted:
+		t.stepCount = e.Step
+	case e.Type == agent.EventToolExecuted && e.Action != nil:
+		t.steps = append(t.steps, *e.Action)
+	}
+	t.notify()
+}
+
+func (t *Task) finish(result *entity.TaskResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.result = result
+	t.stepCount = result.Steps
+	t.Status = result.Status
+	t.FinishedAt = time.Now()
+	t.cancel = nil
+	t.notify()
+}
+
+// Cancel отменяет задачу: в очереди — сразу, во время выполнения — через ctx.
+// false — задача уже завершена.
+func (t *Task) Cancel() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch {
+	case t.Status == entity.TaskQueued:
+		t.Status = entity.TaskCancelled
+		t.FinishedAt = time.Now()
+		t.result = &entity.TaskResult{
+			Task:    t.Prompt,
+			Status:  entity.TaskCancelled,
+			Error:   "cancelled before start",
+			History: []entity.ActionRecord{},
+		}
+		t.notify()
+		return true
+	case t.cancel != nil:
+		t.cancel()
+		return true
+	default:
+		return false
+	}
+}
+
+// finishedAt — когда задача завершилась; false — еще в очереди или выполняется
+func (t *Task) finishedAt() (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.FinishedAt, t.finished()
+}
+
+func (t *Task) finished() bool {
+	return t.Status != entity.TaskQueued && t.Status != entity.TaskRunning
+}
+
+// notify будит всех, кто ждет изменений (вызывать под t.mu)
+func (t *Task) notify() {
+	close(t.changed)
+	t.changed = make(chan struct{})
+}